@@ -4,12 +4,15 @@ go 1.24.3
 
 replace go1f => ./
 
-require go1f v0.0.0
+require (
+	github.com/golang-jwt/jwt/v5 v5.2.2
+	go1f v0.0.0
+	modernc.org/sqlite v1.37.1
+)
 
 require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/dustin/go-humanize v1.0.1 // indirect
-	github.com/golang-jwt/jwt/v5 v5.2.2 // indirect
 	github.com/google/uuid v1.6.0 // indirect
 	github.com/jmoiron/sqlx v1.4.0 // indirect
 	github.com/mattn/go-isatty v0.0.20 // indirect
@@ -23,5 +26,4 @@ require (
 	modernc.org/libc v1.65.7 // indirect
 	modernc.org/mathutil v1.7.1 // indirect
 	modernc.org/memory v1.11.0 // indirect
-	modernc.org/sqlite v1.37.1 // indirect
 )
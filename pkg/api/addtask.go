@@ -1,10 +1,16 @@
 package api
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"go1f/pkg/db"
@@ -13,12 +19,26 @@ import (
 type taskResponse struct {
 	ID    int64  `json:"id,omitempty"`
 	Error string `json:"error,omitempty"`
+	// Duplicates - похожие открытые задачи, предупреждение о возможном дубликате
+	Duplicates []int64 `json:"duplicates,omitempty"`
 }
 
+// idempotencyKeyHeader - заголовок с ключом идемпотентности добавления задачи
+const idempotencyKeyHeader = "Idempotency-Key"
+
 func addTaskHandler(w http.ResponseWriter, r *http.Request) {
 	var task db.Task
 	var response taskResponse
 
+	// Ключ позволяет клиенту безопасно повторить запрос: задача добавится
+	// один раз, а повтор получит тот же ответ
+	key := strings.TrimSpace(r.Header.Get(idempotencyKeyHeader))
+	if len(key) > db.MaxIdempotencyKeyLength {
+		response.Error = "Idempotency key is too long"
+		writeJSON(w, response, http.StatusBadRequest)
+		return
+	}
+
 	if err := json.NewDecoder(r.Body).Decode(&task); err != nil {
 		log.Printf("JSON decode error: %v", err)
 		response.Error = "Invalid JSON format"
@@ -33,6 +53,18 @@ func addTaskHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// force=true добавляет задачу без поиска похожих
+	force, _ := strconv.ParseBool(r.URL.Query().Get("force"))
+
+	// Хэш считается до подстановки дат по умолчанию, которые зависят от дня повтора
+	hash, err := addTaskRequestHash(&task, force)
+	if err != nil {
+		log.Printf("Request hash error: %v", err)
+		response.Error = "Failed to process request"
+		writeJSON(w, response, http.StatusInternalServerError)
+		return
+	}
+
 	now := time.Now()
 	if err := processTaskDate(&task, now); err != nil {
 		log.Printf("Date processing error: %v", err)
@@ -41,7 +73,28 @@ func addTaskHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	id, err := db.AddTask(&task)
+	res, err := db.AddTaskWithOptions(r.Context(), &task, db.AddTaskOptions{
+		IdempotencyKey: key,
+		RequestHash:    hash,
+		SkipDuplicates: force,
+		Response:       addTaskResponse,
+	})
+	if errors.Is(err, db.ErrIdempotencyMismatch) {
+		response.Error = err.Error()
+		writeJSON(w, response, http.StatusUnprocessableEntity)
+		return
+	}
+	if errors.Is(err, db.ErrInvalidTag) || errors.Is(err, db.ErrProjectNotFound) ||
+		errors.Is(err, db.ErrInvalidPriority) || errors.Is(err, db.ErrInvalidStatus) {
+		response.Error = err.Error()
+		writeJSON(w, response, http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, db.ErrProjectArchived) {
+		response.Error = err.Error()
+		writeJSON(w, response, http.StatusConflict)
+		return
+	}
 	if err != nil {
 		log.Printf("Database error: %v", err)
 		response.Error = "Failed to add task to database"
@@ -49,12 +102,53 @@ func addTaskHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	log.Printf("Task added successfully, ID: %d", id)
-	response.ID = id
+	if res.Replayed {
+		log.Printf("Replayed task creation for idempotency key, ID: %d", res.ID)
+		w.Header().Set("Idempotent-Replayed", "true")
+		// Ключи, сохранённые до появления ответа в базе, хранят только ID
+		if res.Response != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(res.Status)
+			w.Write(res.Response)
+			return
+		}
+	} else {
+		log.Printf("Task added successfully, ID: %d", res.ID)
+	}
+	response.ID = res.ID
+	response.Duplicates = res.Duplicates
 	writeJSON(w, response, http.StatusOK)
 }
 
+// addTaskResponse формирует ответ на добавление задачи для сохранения
+// с ключом идемпотентности, в том же виде, что пишет writeJSON
+func addTaskResponse(res db.AddTaskResult) (int, []byte, error) {
+	var buf bytes.Buffer
+	err := json.NewEncoder(&buf).Encode(taskResponse{ID: res.ID, Duplicates: res.Duplicates})
+	return http.StatusOK, buf.Bytes(), err
+}
+
+// addTaskRequestHash возвращает хэш запроса на добавление задачи для сравнения
+// повторов с тем же ключом идемпотентности. Хэшируется разобранная задача
+// вместе с параметрами запроса, поэтому другое форматирование или порядок
+// полей JSON не делают повтор другим запросом.
+func addTaskRequestHash(task *db.Task, force bool) (string, error) {
+	data, err := json.Marshal(struct {
+		Task  *db.Task `json:"task"`
+		Force bool     `json:"force"`
+	}{task, force})
+	if err != nil {
+		return "", err
+	}
+	hash := sha256.Sum256(data)
+	return hex.EncodeToString(hash[:]), nil
+}
+
 func processTaskDate(task *db.Task, now time.Time) error {
+	if err := checkDue(task.Due); err != nil {
+		return err
+	}
+
 	// Если дата не указана - используем сегодня
 	if task.Date == "" {
 		task.Date = now.Format(dateFormat)
@@ -73,6 +167,11 @@ func processTaskDate(task *db.Task, now time.Time) error {
 		if err != nil {
 			return err
 		}
+		// Срок сдвигается вместе с датой повторения
+		task.Due, err = db.ShiftDue(task.Due, task.Date, next)
+		if err != nil {
+			return err
+		}
 		task.Date = next
 	} else if date.Before(now) {
 		// Если дата в прошлом и нет правила повторения - используем сегодня
@@ -81,3 +180,14 @@ func processTaskDate(task *db.Task, now time.Time) error {
 
 	return nil
 }
+
+// checkDue проверяет формат необязательного срока выполнения
+func checkDue(due string) error {
+	if due == "" {
+		return nil
+	}
+	if _, err := time.Parse(dateFormat, due); err != nil {
+		return fmt.Errorf("invalid due date format, expected YYYYMMDD")
+	}
+	return nil
+}
@@ -7,9 +7,31 @@ import (
 
 func Init() {
 	http.HandleFunc("/api/nextdate", nextDateHandler)
-	http.HandleFunc("/api/task", taskHandler)
-	http.HandleFunc("/api/addtask", addTaskHandler)
-	http.HandleFunc("/api/tasks", getTaskListHandler)
+	http.HandleFunc("/api/signin", signinHandler)
+	http.HandleFunc("/api/task", ActorMiddleware(taskHandler))
+	http.HandleFunc("/api/task/done", ActorMiddleware(taskDoneHandler))
+	http.HandleFunc("/api/task/status", ActorMiddleware(taskStatusHandler))
+	http.HandleFunc("/api/task/clone", ActorMiddleware(taskCloneHandler))
+	http.HandleFunc("/api/task/move", ActorMiddleware(taskMoveHandler))
+	http.HandleFunc("/api/task/reorder", ActorMiddleware(taskReorderHandler))
+	http.HandleFunc("/api/task/dependencies", ActorMiddleware(dependenciesHandler))
+	http.HandleFunc("/api/task/history", ActorMiddleware(taskHistoryHandler))
+	http.HandleFunc("/api/history", ActorMiddleware(historyHandler))
+	http.HandleFunc("/api/trash", ActorMiddleware(trashHandler))
+	http.HandleFunc("/api/trash/restore", ActorMiddleware(trashRestoreHandler))
+	http.HandleFunc("/api/audit", ActorMiddleware(auditHandler))
+	http.HandleFunc("/api/audit/revert", ActorMiddleware(auditRevertHandler))
+	// Вложения доступны только по действительному токену
+	http.HandleFunc("/api/attachments", AuthMiddleware(ActorMiddleware(attachmentsHandler)))
+	http.HandleFunc("/api/notes", ActorMiddleware(notesHandler))
+	http.HandleFunc("/api/checklist", ActorMiddleware(checklistHandler))
+	http.HandleFunc("/api/tags", ActorMiddleware(tagsHandler))
+	http.HandleFunc("/api/projects", ActorMiddleware(projectsHandler))
+	http.HandleFunc("/api/templates", ActorMiddleware(templatesHandler))
+	http.HandleFunc("/api/templates/{id}/instantiate", ActorMiddleware(templateInstantiateHandler))
+	http.HandleFunc("/api/addtask", ActorMiddleware(addTaskHandler))
+	http.HandleFunc("/api/tasks", ActorMiddleware(getTaskListHandler))
+	http.HandleFunc("/api/tasks/batch", ActorMiddleware(tasksBatchHandler))
 }
 
 func nextDateHandler(w http.ResponseWriter, r *http.Request) {
@@ -0,0 +1,150 @@
+package api
+
+import (
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go1f/pkg/db"
+)
+
+// attachmentField - имя поля формы с файлом при загрузке
+const attachmentField = "file"
+
+// AttachmentsResponse - структура для ответа со списком вложений задачи
+type AttachmentsResponse struct {
+	Attachments []*db.Attachment `json:"attachments"`
+}
+
+// attachmentsHandler загружает, отдаёт и удаляет вложения задач:
+//
+//	GET    ?task_id=N - список вложений задачи
+//	GET    ?id=N      - содержимое файла
+//	POST   ?task_id=N - загрузка файла из поля file формы multipart/form-data
+//	DELETE ?id=N      - удаление вложения
+func attachmentsHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		if r.URL.Query().Has("task_id") {
+			listAttachments(w, r)
+			return
+		}
+		downloadAttachment(w, r)
+
+	case http.MethodPost:
+		uploadAttachment(w, r)
+
+	case http.MethodDelete:
+		id, ok := requireID(w, r)
+		if !ok {
+			return
+		}
+		if err := db.DeleteAttachment(id); err != nil {
+			writeAttachmentError(w, err)
+			return
+		}
+		writeJSON(w, struct{}{}, http.StatusOK)
+
+	default:
+		writeJSON(w, ErrorResponse{Error: "Method not allowed"}, http.StatusMethodNotAllowed)
+	}
+}
+
+func taskIDParam(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	taskID, err := strconv.ParseInt(r.URL.Query().Get("task_id"), 10, 64)
+	if err != nil {
+		writeJSON(w, ErrorResponse{Error: "Invalid task_id"}, http.StatusBadRequest)
+		return 0, false
+	}
+	return taskID, true
+}
+
+func listAttachments(w http.ResponseWriter, r *http.Request) {
+	taskID, ok := taskIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	list, err := db.Attachments(taskID)
+	if err != nil {
+		writeAttachmentError(w, err)
+		return
+	}
+	writeJSON(w, AttachmentsResponse{Attachments: list}, http.StatusOK)
+}
+
+func downloadAttachment(w http.ResponseWriter, r *http.Request) {
+	id, ok := requireID(w, r)
+	if !ok {
+		return
+	}
+
+	a, file, err := db.OpenAttachment(id)
+	if err != nil {
+		writeAttachmentError(w, err)
+		return
+	}
+	defer file.Close()
+
+	w.Header().Set("Content-Type", a.MIMEType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": a.Name}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+
+	modTime, _ := time.Parse(time.RFC3339, a.CreatedAt)
+	http.ServeContent(w, r, a.Name, modTime, file)
+}
+
+// uploadAttachment читает файл потоком, не сохраняя форму целиком в памяти
+func uploadAttachment(w http.ResponseWriter, r *http.Request) {
+	taskID, ok := taskIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	reader, err := r.MultipartReader()
+	if err != nil {
+		writeJSON(w, ErrorResponse{Error: "Expected multipart/form-data"}, http.StatusBadRequest)
+		return
+	}
+
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			writeJSON(w, ErrorResponse{Error: "File field is required"}, http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			writeJSON(w, ErrorResponse{Error: "Invalid multipart form"}, http.StatusBadRequest)
+			return
+		}
+		if part.FormName() != attachmentField {
+			part.Close()
+			continue
+		}
+
+		a, err := db.AddAttachment(taskID, part.FileName(), part)
+		part.Close()
+		if err != nil {
+			writeAttachmentError(w, err)
+			return
+		}
+		writeJSON(w, a, http.StatusOK)
+		return
+	}
+}
+
+func writeAttachmentError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, db.ErrNotFound), errors.Is(err, db.ErrAttachmentNotFound):
+		writeJSON(w, ErrorResponse{Error: err.Error()}, http.StatusNotFound)
+	case errors.Is(err, db.ErrAttachmentTooLarge):
+		writeJSON(w, ErrorResponse{Error: err.Error()}, http.StatusRequestEntityTooLarge)
+	case errors.Is(err, db.ErrAttachmentType):
+		writeJSON(w, ErrorResponse{Error: err.Error()}, http.StatusUnsupportedMediaType)
+	default:
+		writeJSON(w, ErrorResponse{Error: err.Error()}, http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,65 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"go1f/pkg/db"
+)
+
+// AuditResponse - структура для ответа с журналом изменений
+type AuditResponse struct {
+	Entries []*db.AuditEntry `json:"entries"`
+}
+
+// auditHandler возвращает журнал изменений с фильтрацией по задаче и периоду
+func auditHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, ErrorResponse{Error: "Method not allowed"}, http.StatusMethodNotAllowed)
+		return
+	}
+
+	from, to, limit, err := parseRange(r)
+	if err != nil {
+		writeJSON(w, ErrorResponse{Error: err.Error()}, http.StatusBadRequest)
+		return
+	}
+	filter := db.AuditFilter{From: from, To: to, Limit: limit}
+
+	if taskIDStr := r.URL.Query().Get("task_id"); taskIDStr != "" {
+		filter.TaskID, err = strconv.ParseInt(taskIDStr, 10, 64)
+		if err != nil {
+			writeJSON(w, ErrorResponse{Error: "Invalid task_id format"}, http.StatusBadRequest)
+			return
+		}
+	}
+
+	entries, err := db.AuditLog(filter)
+	if err != nil {
+		writeJSON(w, ErrorResponse{Error: err.Error()}, http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, AuditResponse{Entries: entries}, http.StatusOK)
+}
+
+// auditRevertHandler возвращает задачу к состоянию до изменения из записи журнала
+func auditRevertHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, ErrorResponse{Error: "Method not allowed"}, http.StatusMethodNotAllowed)
+		return
+	}
+
+	id, ok := requireID(w, r)
+	if !ok {
+		return
+	}
+
+	taskID, err := db.RevertAudit(r.Context(), id)
+	if err != nil {
+		writeTaskError(w, err)
+		return
+	}
+
+	writeJSON(w, taskResponse{ID: taskID}, http.StatusOK)
+}
@@ -0,0 +1,109 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"go1f/pkg/db"
+)
+
+// batchRequest - пакет действий над задачами: список действий и/или поиск
+// с действием для каждой найденной задачи
+type batchRequest struct {
+	Actions []db.BatchOp        `json:"actions"`
+	Search  *batchSearchRequest `json:"search"`
+	DryRun  bool                `json:"dry_run"`
+}
+
+// batchSearchRequest выбирает задачи как /api/tasks и применяет к ним
+// действие done, delete или move_date
+type batchSearchRequest struct {
+	Query     string   `json:"query"`
+	Tags      []string `json:"tags"`
+	ProjectID int64    `json:"project_id"`
+	Action    string   `json:"action"`
+	Date      string   `json:"date"`
+	Note      string   `json:"note"`
+	Force     bool     `json:"force"`
+}
+
+// BatchResponse - отчёт о выполнении пакета. Applied означает, что
+// изменения сохранены; при ошибке хотя бы одного действия не сохраняется ничего.
+type BatchResponse struct {
+	Applied bool             `json:"applied"`
+	DryRun  bool             `json:"dry_run,omitempty"`
+	Results []db.BatchResult `json:"results"`
+	Error   string           `json:"error,omitempty"`
+}
+
+// tasksBatchHandler выполняет пакет действий в одной транзакции
+func tasksBatchHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, ErrorResponse{Error: "Method not allowed"}, http.StatusMethodNotAllowed)
+		return
+	}
+
+	now, err := clientNow(r)
+	if err != nil {
+		writeJSON(w, ErrorResponse{Error: err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	var req batchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, ErrorResponse{Error: "Invalid JSON format"}, http.StatusBadRequest)
+		return
+	}
+
+	batch := db.BatchRequest{
+		Ops:         req.Actions,
+		DryRun:      req.DryRun,
+		NextDate:    NextDate,
+		PrepareTask: prepareBatchTask,
+	}
+	if s := req.Search; s != nil {
+		// Пустой поиск выбрал бы все задачи - это почти наверняка ошибка
+		if s.Query == "" && len(s.Tags) == 0 && s.ProjectID == 0 {
+			writeJSON(w, ErrorResponse{Error: "Search requires query, tags or project_id"}, http.StatusBadRequest)
+			return
+		}
+		batch.Search = &db.BatchSearch{
+			Query: db.TaskQuery{Search: s.Query, Now: now, Tags: s.Tags, ProjectID: s.ProjectID},
+			Op:    db.BatchOp{Action: s.Action, Date: s.Date, Note: s.Note, Force: s.Force},
+		}
+	}
+
+	results, err := db.Batch(r.Context(), batch)
+	switch {
+	case err == nil:
+		writeJSON(w, BatchResponse{Applied: !req.DryRun, DryRun: req.DryRun, Results: results}, http.StatusOK)
+	case errors.Is(err, db.ErrBatchFailed):
+		writeJSON(w, BatchResponse{DryRun: req.DryRun, Results: results, Error: err.Error()}, http.StatusConflict)
+	case errors.Is(err, db.ErrInvalidBatch), errors.Is(err, db.ErrInvalidQuery),
+		errors.Is(err, db.ErrInvalidTag), errors.Is(err, db.ErrInvalidStatus):
+		writeJSON(w, ErrorResponse{Error: err.Error()}, http.StatusBadRequest)
+	default:
+		writeJSON(w, ErrorResponse{Error: err.Error()}, http.StatusInternalServerError)
+	}
+}
+
+// prepareBatchTask проверяет задачу действия add или update так же,
+// как /api/addtask и PUT /api/task
+func prepareBatchTask(action string, task *db.Task) error {
+	if task.Title == "" {
+		return errors.New("task title is required")
+	}
+
+	if action == db.BatchAdd {
+		return processTaskDate(task, time.Now())
+	}
+
+	if task.Date == "" {
+		task.Date = time.Now().Format(dateFormat)
+	} else if _, err := time.Parse(dateFormat, task.Date); err != nil {
+		return errors.New("invalid date format, expected YYYYMMDD")
+	}
+	return checkDue(task.Due)
+}
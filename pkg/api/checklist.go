@@ -0,0 +1,108 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"go1f/pkg/db"
+)
+
+// ChecklistResponse - структура для ответа с пунктами чек-листа
+type ChecklistResponse struct {
+	Items []*db.ChecklistItem `json:"items"`
+}
+
+// checklistUpdateRequest - изменение пункта. Position необязательна:
+// без неё пункт остаётся на месте.
+type checklistUpdateRequest struct {
+	ID       int64  `json:"id"`
+	Title    string `json:"title"`
+	Done     bool   `json:"done"`
+	Position *int   `json:"position"`
+}
+
+// checklistHandler - CRUD для пунктов чек-листа задачи
+func checklistHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		taskID, err := strconv.ParseInt(r.URL.Query().Get("task_id"), 10, 64)
+		if err != nil {
+			writeJSON(w, ErrorResponse{Error: "Invalid task_id"}, http.StatusBadRequest)
+			return
+		}
+
+		items, err := db.Checklist(taskID)
+		if err != nil {
+			writeChecklistError(w, err)
+			return
+		}
+		writeJSON(w, ChecklistResponse{Items: items}, http.StatusOK)
+
+	case http.MethodPost:
+		var item db.ChecklistItem
+		if err := json.NewDecoder(r.Body).Decode(&item); err != nil {
+			writeJSON(w, ErrorResponse{Error: "Invalid JSON format"}, http.StatusBadRequest)
+			return
+		}
+		if item.TaskID == 0 {
+			writeJSON(w, ErrorResponse{Error: "Task ID is required"}, http.StatusBadRequest)
+			return
+		}
+
+		id, err := db.AddChecklistItem(&item)
+		if err != nil {
+			writeChecklistError(w, err)
+			return
+		}
+		writeJSON(w, taskResponse{ID: id}, http.StatusOK)
+
+	case http.MethodPut:
+		var req checklistUpdateRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, ErrorResponse{Error: "Invalid JSON format"}, http.StatusBadRequest)
+			return
+		}
+		if req.ID == 0 {
+			writeJSON(w, ErrorResponse{Error: "Item ID is required"}, http.StatusBadRequest)
+			return
+		}
+
+		item := db.ChecklistItem{ID: req.ID, Title: req.Title, Done: req.Done}
+		if req.Position != nil {
+			item.Position = *req.Position
+		}
+		if err := db.UpdateChecklistItem(&item, req.Position != nil); err != nil {
+			writeChecklistError(w, err)
+			return
+		}
+		writeJSON(w, struct{}{}, http.StatusOK)
+
+	case http.MethodDelete:
+		id, ok := requireID(w, r)
+		if !ok {
+			return
+		}
+
+		if err := db.DeleteChecklistItem(id); err != nil {
+			writeChecklistError(w, err)
+			return
+		}
+		writeJSON(w, struct{}{}, http.StatusOK)
+
+	default:
+		writeJSON(w, ErrorResponse{Error: "Method not allowed"}, http.StatusMethodNotAllowed)
+	}
+}
+
+func writeChecklistError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, db.ErrInvalidChecklistItem):
+		writeJSON(w, ErrorResponse{Error: err.Error()}, http.StatusBadRequest)
+	case errors.Is(err, db.ErrNotFound), errors.Is(err, db.ErrChecklistItemNotFound):
+		writeJSON(w, ErrorResponse{Error: err.Error()}, http.StatusNotFound)
+	default:
+		writeJSON(w, ErrorResponse{Error: err.Error()}, http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,64 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"go1f/pkg/db"
+)
+
+// dependencyRequest - зависимость задачи TaskID от задачи BlockerID
+type dependencyRequest struct {
+	TaskID    int64 `json:"task_id"`
+	BlockerID int64 `json:"blocker_id"`
+}
+
+// dependenciesHandler добавляет и удаляет зависимости между задачами.
+// Сами связи отдаются в полях blocked_by и blocks задачи.
+func dependenciesHandler(w http.ResponseWriter, r *http.Request) {
+	var req dependencyRequest
+
+	switch r.Method {
+	case http.MethodPost:
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, ErrorResponse{Error: "Invalid JSON format"}, http.StatusBadRequest)
+			return
+		}
+	case http.MethodDelete:
+		var err error
+		req.TaskID, err = strconv.ParseInt(r.URL.Query().Get("task_id"), 10, 64)
+		if err == nil {
+			req.BlockerID, err = strconv.ParseInt(r.URL.Query().Get("blocker_id"), 10, 64)
+		}
+		if err != nil {
+			writeJSON(w, ErrorResponse{Error: "Invalid task_id or blocker_id"}, http.StatusBadRequest)
+			return
+		}
+	default:
+		writeJSON(w, ErrorResponse{Error: "Method not allowed"}, http.StatusMethodNotAllowed)
+		return
+	}
+
+	if req.TaskID == 0 || req.BlockerID == 0 {
+		writeJSON(w, ErrorResponse{Error: "task_id and blocker_id are required"}, http.StatusBadRequest)
+		return
+	}
+
+	var err error
+	if r.Method == http.MethodPost {
+		err = db.AddDependency(r.Context(), req.TaskID, req.BlockerID)
+	} else {
+		err = db.DeleteDependency(r.Context(), req.TaskID, req.BlockerID)
+	}
+
+	switch {
+	case err == nil:
+		writeJSON(w, struct{}{}, http.StatusOK)
+	case errors.Is(err, db.ErrDependencyNotFound):
+		writeJSON(w, ErrorResponse{Error: err.Error()}, http.StatusNotFound)
+	default:
+		writeTaskError(w, err)
+	}
+}
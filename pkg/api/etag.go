@@ -0,0 +1,40 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"go1f/pkg/db"
+)
+
+var errInvalidIfMatch = errors.New("invalid If-Match header")
+
+// taskETag формирует значение ETag по версии задачи
+func taskETag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// parseIfMatch извлекает ожидаемую версию задачи из заголовка If-Match.
+// Отсутствующий заголовок и "*" дают 0, что означает "без проверки".
+// Некорректный заголовок - ошибка запроса, а не несовпадение версии.
+// If-Match требует строгого сравнения, поэтому слабый валидатор W/"..."
+// никогда не совпадает и даёт ErrVersionMismatch.
+func parseIfMatch(r *http.Request) (int64, error) {
+	value := strings.TrimSpace(r.Header.Get("If-Match"))
+	if value == "" || value == "*" {
+		return 0, nil
+	}
+
+	if strings.HasPrefix(value, "W/") {
+		return 0, db.ErrVersionMismatch
+	}
+	value = strings.Trim(value, `"`)
+
+	version, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || version <= 0 {
+		return 0, errInvalidIfMatch
+	}
+	return version, nil
+}
@@ -0,0 +1,101 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go1f/pkg/db"
+)
+
+const (
+	defaultHistoryLimit = 50
+	maxHistoryLimit     = 500
+)
+
+// HistoryResponse - структура для ответа с историей выполнения задач
+type HistoryResponse struct {
+	Completions []*db.Completion `json:"completions"`
+}
+
+// taskHistoryHandler возвращает историю выполнения одной задачи
+func taskHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, ErrorResponse{Error: "Method not allowed"}, http.StatusMethodNotAllowed)
+		return
+	}
+
+	id, ok := requireID(w, r)
+	if !ok {
+		return
+	}
+
+	writeHistory(w, r, id)
+}
+
+// historyHandler возвращает общую ленту выполненных задач
+func historyHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, ErrorResponse{Error: "Method not allowed"}, http.StatusMethodNotAllowed)
+		return
+	}
+
+	writeHistory(w, r, 0)
+}
+
+func writeHistory(w http.ResponseWriter, r *http.Request, taskID int64) {
+	filter, err := parseHistoryFilter(r)
+	if err != nil {
+		writeJSON(w, ErrorResponse{Error: err.Error()}, http.StatusBadRequest)
+		return
+	}
+	filter.TaskID = taskID
+
+	completions, err := db.History(filter)
+	if err != nil {
+		writeJSON(w, ErrorResponse{Error: err.Error()}, http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, HistoryResponse{Completions: completions}, http.StatusOK)
+}
+
+// parseHistoryFilter разбирает параметры выборки из истории выполнения
+func parseHistoryFilter(r *http.Request) (db.HistoryFilter, error) {
+	from, to, limit, err := parseRange(r)
+	return db.HistoryFilter{From: from, To: to, Limit: limit}, err
+}
+
+// parseRange разбирает общие для журналов параметры from и to
+// (включительно, YYYYMMDD) и limit
+func parseRange(r *http.Request) (from, to time.Time, limit int, err error) {
+	query := r.URL.Query()
+	limit = defaultHistoryLimit
+
+	if fromStr := query.Get("from"); fromStr != "" {
+		from, err = time.ParseInLocation(dateFormat, fromStr, time.Local)
+		if err != nil {
+			return from, to, limit, fmt.Errorf("invalid from date, expected YYYYMMDD")
+		}
+	}
+
+	if toStr := query.Get("to"); toStr != "" {
+		date, err := time.ParseInLocation(dateFormat, toStr, time.Local)
+		if err != nil {
+			return from, to, limit, fmt.Errorf("invalid to date, expected YYYYMMDD")
+		}
+		// Граница включительная: берём всё до начала следующего дня
+		to = date.AddDate(0, 0, 1)
+	}
+
+	if limitStr := query.Get("limit"); limitStr != "" {
+		n, err := strconv.Atoi(limitStr)
+		if err != nil || n <= 0 {
+			return from, to, limit, fmt.Errorf("invalid limit")
+		}
+		limit = min(n, maxHistoryLimit)
+	}
+
+	return from, to, limit, nil
+}
@@ -5,8 +5,12 @@ import (
 	"strings"
 
 	"go1f/pkg/auth"
+	"go1f/pkg/db"
 )
 
+// anonymousActor записывается в журнал изменений, если запрос без токена
+const anonymousActor = "anonymous"
+
 func AuthMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Пропускаем аутентификацию для signin
@@ -15,6 +19,12 @@ func AuthMiddleware(next http.HandlerFunc) http.HandlerFunc {
 			return
 		}
 
+		// Без пароля аутентификация отключена
+		if !auth.Enabled() {
+			next(w, r)
+			return
+		}
+
 		// Проверяем токен
 		tokenString := extractToken(r)
 		if tokenString == "" {
@@ -32,6 +42,20 @@ func AuthMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	}
 }
 
+// ActorMiddleware передаёт в контекст запроса автора изменений для журнала
+func ActorMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		actor := anonymousActor
+		if tokenString := extractToken(r); tokenString != "" {
+			if subject, err := auth.TokenSubject(tokenString); err == nil && subject != "" {
+				actor = subject
+			}
+		}
+
+		next(w, r.WithContext(db.WithActor(r.Context(), actor)))
+	}
+}
+
 func extractToken(r *http.Request) string {
 	// Из заголовка Authorization
 	bearerToken := r.Header.Get("Authorization")
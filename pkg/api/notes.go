@@ -0,0 +1,94 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"go1f/pkg/db"
+)
+
+// NotesResponse - структура для ответа с заметками задачи
+type NotesResponse struct {
+	Notes []*db.Note `json:"notes"`
+}
+
+// notesHandler - заметки задачи: список по task_id, добавление,
+// правка текста и удаление. Автор заметки берётся из токена.
+func notesHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		taskID, ok := taskIDParam(w, r)
+		if !ok {
+			return
+		}
+
+		notes, err := db.Notes(taskID)
+		if err != nil {
+			writeNoteError(w, err)
+			return
+		}
+		writeJSON(w, NotesResponse{Notes: notes}, http.StatusOK)
+
+	case http.MethodPost:
+		var note db.Note
+		if err := json.NewDecoder(r.Body).Decode(&note); err != nil {
+			writeJSON(w, ErrorResponse{Error: "Invalid JSON format"}, http.StatusBadRequest)
+			return
+		}
+		if note.TaskID == 0 {
+			writeJSON(w, ErrorResponse{Error: "Task ID is required"}, http.StatusBadRequest)
+			return
+		}
+
+		id, err := db.AddNote(r.Context(), &note)
+		if err != nil {
+			writeNoteError(w, err)
+			return
+		}
+		writeJSON(w, taskResponse{ID: id}, http.StatusOK)
+
+	case http.MethodPut:
+		var note db.Note
+		if err := json.NewDecoder(r.Body).Decode(&note); err != nil {
+			writeJSON(w, ErrorResponse{Error: "Invalid JSON format"}, http.StatusBadRequest)
+			return
+		}
+		if note.ID == 0 {
+			writeJSON(w, ErrorResponse{Error: "Note ID is required"}, http.StatusBadRequest)
+			return
+		}
+
+		if err := db.UpdateNote(note.ID, note.Text); err != nil {
+			writeNoteError(w, err)
+			return
+		}
+		writeJSON(w, struct{}{}, http.StatusOK)
+
+	case http.MethodDelete:
+		id, ok := requireID(w, r)
+		if !ok {
+			return
+		}
+
+		if err := db.DeleteNote(id); err != nil {
+			writeNoteError(w, err)
+			return
+		}
+		writeJSON(w, struct{}{}, http.StatusOK)
+
+	default:
+		writeJSON(w, ErrorResponse{Error: "Method not allowed"}, http.StatusMethodNotAllowed)
+	}
+}
+
+func writeNoteError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, db.ErrInvalidNote):
+		writeJSON(w, ErrorResponse{Error: err.Error()}, http.StatusBadRequest)
+	case errors.Is(err, db.ErrNotFound), errors.Is(err, db.ErrNoteNotFound):
+		writeJSON(w, ErrorResponse{Error: err.Error()}, http.StatusNotFound)
+	default:
+		writeJSON(w, ErrorResponse{Error: err.Error()}, http.StatusInternalServerError)
+	}
+}
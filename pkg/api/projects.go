@@ -0,0 +1,133 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"go1f/pkg/db"
+)
+
+// ProjectsResponse - структура для ответа со списком проектов
+type ProjectsResponse struct {
+	Projects []*db.Project `json:"projects"`
+}
+
+// taskMoveRequest - тело запроса на перенос задачи в другой проект
+type taskMoveRequest struct {
+	ProjectID int64 `json:"project_id"`
+}
+
+// projectsHandler - CRUD для проектов. Архивные проекты показываются
+// в списке только с параметром archived=true.
+func projectsHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		withArchived, _ := strconv.ParseBool(r.URL.Query().Get("archived"))
+		projects, err := db.Projects(withArchived)
+		if err != nil {
+			writeJSON(w, ErrorResponse{Error: err.Error()}, http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, ProjectsResponse{Projects: projects}, http.StatusOK)
+
+	case http.MethodPost:
+		var project db.Project
+		if err := json.NewDecoder(r.Body).Decode(&project); err != nil {
+			writeJSON(w, ErrorResponse{Error: "Invalid JSON format"}, http.StatusBadRequest)
+			return
+		}
+
+		id, err := db.AddProject(&project)
+		if err != nil {
+			writeProjectError(w, err)
+			return
+		}
+		writeJSON(w, taskResponse{ID: id}, http.StatusOK)
+
+	case http.MethodPut:
+		var project db.Project
+		if err := json.NewDecoder(r.Body).Decode(&project); err != nil {
+			writeJSON(w, ErrorResponse{Error: "Invalid JSON format"}, http.StatusBadRequest)
+			return
+		}
+		if project.ID == 0 {
+			writeJSON(w, ErrorResponse{Error: "Project ID is required"}, http.StatusBadRequest)
+			return
+		}
+
+		if err := db.UpdateProject(&project); err != nil {
+			writeProjectError(w, err)
+			return
+		}
+		writeJSON(w, struct{}{}, http.StatusOK)
+
+	case http.MethodDelete:
+		id, ok := requireID(w, r)
+		if !ok {
+			return
+		}
+
+		if err := db.DeleteProject(r.Context(), id); err != nil {
+			writeProjectError(w, err)
+			return
+		}
+		writeJSON(w, struct{}{}, http.StatusOK)
+
+	default:
+		writeJSON(w, ErrorResponse{Error: "Method not allowed"}, http.StatusMethodNotAllowed)
+	}
+}
+
+func writeProjectError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, db.ErrInvalidProject), errors.Is(err, db.ErrInboxProject):
+		writeJSON(w, ErrorResponse{Error: err.Error()}, http.StatusBadRequest)
+	case errors.Is(err, db.ErrProjectExists):
+		writeJSON(w, ErrorResponse{Error: err.Error()}, http.StatusConflict)
+	case errors.Is(err, db.ErrProjectNotFound):
+		writeJSON(w, ErrorResponse{Error: err.Error()}, http.StatusNotFound)
+	default:
+		writeJSON(w, ErrorResponse{Error: err.Error()}, http.StatusInternalServerError)
+	}
+}
+
+// taskMoveHandler переносит задачу в другой проект
+func taskMoveHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, ErrorResponse{Error: "Method not allowed"}, http.StatusMethodNotAllowed)
+		return
+	}
+
+	id, ok := requireID(w, r)
+	if !ok {
+		return
+	}
+
+	var req taskMoveRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, ErrorResponse{Error: "Invalid JSON format"}, http.StatusBadRequest)
+		return
+	}
+
+	if err := db.MoveTask(r.Context(), id, req.ProjectID); err != nil {
+		writeTaskError(w, err)
+		return
+	}
+
+	writeJSON(w, struct{}{}, http.StatusOK)
+}
+
+// projectParam читает необязательный параметр project - ID проекта
+func projectParam(r *http.Request) (int64, error) {
+	value := r.URL.Query().Get("project")
+	if value == "" {
+		return 0, nil
+	}
+	id, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || id <= 0 {
+		return 0, errors.New("invalid project ID")
+	}
+	return id, nil
+}
@@ -0,0 +1,46 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"go1f/pkg/db"
+)
+
+// taskReorderRequest - новая позиция задачи: сразу после задачи After
+// или сразу перед задачей Before той же даты
+type taskReorderRequest struct {
+	Before int64 `json:"before"`
+	After  int64 `json:"after"`
+}
+
+// taskReorderHandler меняет ручной порядок задачи внутри её даты
+func taskReorderHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, ErrorResponse{Error: "Method not allowed"}, http.StatusMethodNotAllowed)
+		return
+	}
+
+	id, ok := requireID(w, r)
+	if !ok {
+		return
+	}
+
+	var req taskReorderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, ErrorResponse{Error: "Invalid JSON format"}, http.StatusBadRequest)
+		return
+	}
+
+	if err := db.ReorderTask(r.Context(), id, req.Before, req.After); err != nil {
+		if errors.Is(err, db.ErrInvalidReorder) {
+			writeJSON(w, ErrorResponse{Error: err.Error()}, http.StatusBadRequest)
+			return
+		}
+		writeTaskError(w, err)
+		return
+	}
+
+	writeJSON(w, struct{}{}, http.StatusOK)
+}
@@ -0,0 +1,86 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"go1f/pkg/db"
+)
+
+// statusAll в параметре status означает задачи в любом статусе
+const statusAll = "all"
+
+// taskStatusRequest - новый статус задачи. Note сохраняется в истории,
+// если задача переводится в done.
+type taskStatusRequest struct {
+	Status string `json:"status"`
+	Note   string `json:"note"`
+}
+
+// BoardColumn - колонка доски с задачами одного статуса
+type BoardColumn struct {
+	Status string     `json:"status"`
+	Tasks  []*db.Task `json:"tasks"`
+	Total  int        `json:"total"`
+}
+
+// BoardResponse - структура для ответа со списком задач, сгруппированным по статусам
+type BoardResponse struct {
+	Columns []BoardColumn `json:"columns"`
+}
+
+// taskStatusHandler меняет статус задачи. Перевод в done выполняет задачу
+// так же, как /api/task/done.
+func taskStatusHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, ErrorResponse{Error: "Method not allowed"}, http.StatusMethodNotAllowed)
+		return
+	}
+
+	id, ok := requireID(w, r)
+	if !ok {
+		return
+	}
+
+	var req taskStatusRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, ErrorResponse{Error: "Invalid JSON format"}, http.StatusBadRequest)
+		return
+	}
+
+	if req.Status == db.StatusDone {
+		completeTask(w, r, id, req.Note)
+		return
+	}
+
+	if err := db.SetTaskStatus(r.Context(), id, req.Status); err != nil {
+		writeTaskError(w, err)
+		return
+	}
+
+	writeJSON(w, struct{}{}, http.StatusOK)
+}
+
+// statusParams собирает статусы из повторяющегося параметра status,
+// в каждом значении статусы также можно перечислить через запятую.
+// Значение all выбирает все статусы.
+func statusParams(r *http.Request) ([]string, error) {
+	var statuses []string
+	for _, value := range r.URL.Query()["status"] {
+		for _, status := range strings.Split(value, ",") {
+			status = strings.TrimSpace(status)
+			if status == "" {
+				continue
+			}
+			if status == statusAll {
+				return db.Statuses, nil
+			}
+			if err := db.CheckStatus(status); err != nil {
+				return nil, err
+			}
+			statuses = append(statuses, status)
+		}
+	}
+	return statuses, nil
+}
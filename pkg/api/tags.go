@@ -0,0 +1,101 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"go1f/pkg/db"
+)
+
+// TagsResponse - структура для ответа со списком меток
+type TagsResponse struct {
+	Tags []*db.Tag `json:"tags"`
+}
+
+// tagsHandler - CRUD для меток задач
+func tagsHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		tags, err := db.Tags()
+		if err != nil {
+			writeJSON(w, ErrorResponse{Error: err.Error()}, http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, TagsResponse{Tags: tags}, http.StatusOK)
+
+	case http.MethodPost:
+		var tag db.Tag
+		if err := json.NewDecoder(r.Body).Decode(&tag); err != nil {
+			writeJSON(w, ErrorResponse{Error: "Invalid JSON format"}, http.StatusBadRequest)
+			return
+		}
+
+		id, err := db.AddTag(tag.Name)
+		if err != nil {
+			writeTagError(w, err)
+			return
+		}
+		writeJSON(w, taskResponse{ID: id}, http.StatusOK)
+
+	case http.MethodPut:
+		var tag db.Tag
+		if err := json.NewDecoder(r.Body).Decode(&tag); err != nil {
+			writeJSON(w, ErrorResponse{Error: "Invalid JSON format"}, http.StatusBadRequest)
+			return
+		}
+		if tag.ID == 0 {
+			writeJSON(w, ErrorResponse{Error: "Tag ID is required"}, http.StatusBadRequest)
+			return
+		}
+
+		if err := db.RenameTag(r.Context(), tag.ID, tag.Name); err != nil {
+			writeTagError(w, err)
+			return
+		}
+		writeJSON(w, struct{}{}, http.StatusOK)
+
+	case http.MethodDelete:
+		id, ok := requireID(w, r)
+		if !ok {
+			return
+		}
+
+		if err := db.DeleteTag(r.Context(), id); err != nil {
+			writeTagError(w, err)
+			return
+		}
+		writeJSON(w, struct{}{}, http.StatusOK)
+
+	default:
+		writeJSON(w, ErrorResponse{Error: "Method not allowed"}, http.StatusMethodNotAllowed)
+	}
+}
+
+func writeTagError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, db.ErrInvalidTag):
+		writeJSON(w, ErrorResponse{Error: err.Error()}, http.StatusBadRequest)
+	case errors.Is(err, db.ErrTagExists):
+		writeJSON(w, ErrorResponse{Error: err.Error()}, http.StatusConflict)
+	case errors.Is(err, db.ErrTagNotFound):
+		writeJSON(w, ErrorResponse{Error: err.Error()}, http.StatusNotFound)
+	default:
+		writeJSON(w, ErrorResponse{Error: err.Error()}, http.StatusInternalServerError)
+	}
+}
+
+// tagParams собирает метки из повторяющегося параметра tag,
+// в каждом значении метки также можно перечислить через запятую
+func tagParams(r *http.Request) []string {
+	var tags []string
+	for _, value := range r.URL.Query()["tag"] {
+		for _, tag := range strings.Split(value, ",") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				tags = append(tags, tag)
+			}
+		}
+	}
+	return tags
+}
@@ -2,6 +2,7 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 	"time"
@@ -41,6 +42,7 @@ func handleGetTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	w.Header().Set("ETag", taskETag(task.Version))
 	writeJSON(w, task, http.StatusOK)
 }
 
@@ -56,6 +58,13 @@ func handleUpdateTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	version, err := parseIfMatch(r)
+	if err != nil {
+		writeTaskError(w, err)
+		return
+	}
+	task.Version = version
+
 	if task.Title == "" {
 		writeJSON(w, ErrorResponse{Error: "Title is required"}, http.StatusBadRequest)
 		return
@@ -70,12 +79,17 @@ func handleUpdateTask(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 	}
+	if err := checkDue(task.Due); err != nil {
+		writeJSON(w, ErrorResponse{Error: err.Error()}, http.StatusBadRequest)
+		return
+	}
 
-	if err := db.UpdateTask(&task); err != nil {
-		writeJSON(w, ErrorResponse{Error: err.Error()}, http.StatusInternalServerError)
+	if err := db.UpdateTask(r.Context(), &task); err != nil {
+		writeTaskError(w, err)
 		return
 	}
 
+	w.Header().Set("ETag", taskETag(task.Version))
 	writeJSON(w, struct{}{}, http.StatusOK)
 }
 
@@ -93,10 +107,37 @@ func handleDeleteTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := db.DeleteTask(id); err != nil {
-		writeJSON(w, ErrorResponse{Error: err.Error()}, http.StatusInternalServerError)
+	version, err := parseIfMatch(r)
+	if err != nil {
+		writeTaskError(w, err)
+		return
+	}
+
+	if err := db.DeleteTask(r.Context(), id, version); err != nil {
+		writeTaskError(w, err)
 		return
 	}
 
 	writeJSON(w, struct{}{}, http.StatusOK)
 }
+
+// writeTaskError отвечает клиенту кодом, соответствующим ошибке изменения задачи
+func writeTaskError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, db.ErrNotFound):
+		writeJSON(w, ErrorResponse{Error: err.Error()}, http.StatusNotFound)
+	case errors.Is(err, errInvalidIfMatch), errors.Is(err, errInvalidTaskDate),
+		errors.Is(err, db.ErrInvalidTag), errors.Is(err, db.ErrProjectNotFound),
+		errors.Is(err, db.ErrInvalidPriority), errors.Is(err, db.ErrInvalidStatus):
+		writeJSON(w, ErrorResponse{Error: err.Error()}, http.StatusBadRequest)
+	case errors.Is(err, db.ErrProjectArchived):
+		writeJSON(w, ErrorResponse{Error: err.Error()}, http.StatusConflict)
+	case errors.Is(err, db.ErrVersionMismatch):
+		writeJSON(w, ErrorResponse{Error: err.Error()}, http.StatusPreconditionFailed)
+	case errors.Is(err, db.ErrConflict), errors.Is(err, db.ErrTaskBlocked),
+		errors.Is(err, db.ErrDependencyCycle), errors.Is(err, db.ErrInvalidTransition):
+		writeJSON(w, ErrorResponse{Error: err.Error()}, http.StatusConflict)
+	default:
+		writeJSON(w, ErrorResponse{Error: err.Error()}, http.StatusInternalServerError)
+	}
+}
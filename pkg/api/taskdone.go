@@ -1,6 +1,7 @@
 package api
 
 import (
+	"encoding/json"
 	"net/http"
 	"strconv"
 	"time"
@@ -8,6 +9,11 @@ import (
 	"go1f/pkg/db"
 )
 
+// taskDoneRequest - необязательное тело запроса на выполнение задачи
+type taskDoneRequest struct {
+	Note string `json:"note"`
+}
+
 func taskDoneHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		writeJSON(w, ErrorResponse{Error: "Method not allowed"}, http.StatusMethodNotAllowed)
@@ -26,33 +32,50 @@ func taskDoneHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Получаем текущую задачу
-	task, err := db.GetTask(id)
-	if err != nil {
-		writeJSON(w, ErrorResponse{Error: err.Error()}, http.StatusNotFound)
-		return
-	}
-
-	if task.Repeat == "" {
-		// Удаляем одноразовую задачу
-		if err := db.DeleteTask(id); err != nil {
-			writeJSON(w, ErrorResponse{Error: err.Error()}, http.StatusInternalServerError)
-			return
-		}
-	} else {
-		// Обновляем дату для периодической задачи
-		now := time.Now()
-		nextDateStr, err := NextDate(now, task.Date, task.Repeat)
-		if err != nil {
-			writeJSON(w, ErrorResponse{Error: err.Error()}, http.StatusBadRequest)
+	var req taskDoneRequest
+	if r.ContentLength > 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, ErrorResponse{Error: "Invalid JSON format"}, http.StatusBadRequest)
 			return
 		}
+	}
+
+	completeTask(w, r, id, req.Note)
+}
 
-		if err := db.UpdateTaskDate(id, nextDateStr); err != nil {
-			writeJSON(w, ErrorResponse{Error: err.Error()}, http.StatusInternalServerError)
+// completeTask выполняет задачу и записывает ответ клиенту. Параметр date
+// или заголовок If-Match передают дату или версию задачи, которую видел
+// клиент: если задача успела измениться, например уже выполнена с другого
+// устройства, возвращается 409 или 412 и задача не сдвигается повторно.
+func completeTask(w http.ResponseWriter, r *http.Request, id int64, note string) {
+	date := r.URL.Query().Get("date")
+	if date != "" {
+		if _, err := time.Parse(dateFormat, date); err != nil {
+			writeJSON(w, ErrorResponse{Error: "Invalid date format, use YYYYMMDD"}, http.StatusBadRequest)
 			return
 		}
 	}
 
+	version, err := parseIfMatch(r)
+	if err != nil {
+		writeTaskError(w, err)
+		return
+	}
+
+	// Задача с открытыми блокирующими задачами выполняется только с force=true
+	force, _ := strconv.ParseBool(r.URL.Query().Get("force"))
+
+	err = db.CompleteTask(r.Context(), id, db.CompleteOptions{
+		Date:     date,
+		Version:  version,
+		Note:     note,
+		Force:    force,
+		NextDate: NextDate,
+	})
+	if err != nil {
+		writeTaskError(w, err)
+		return
+	}
+
 	writeJSON(w, struct{}{}, http.StatusOK)
 }
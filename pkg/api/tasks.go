@@ -1,13 +1,23 @@
 package api
 
 import (
+	"errors"
 	"go1f/pkg/db"
 	"net/http"
+	"strconv"
+)
+
+const (
+	defaultTasksLimit = 50
+	// maxTasksLimit - максимальный размер страницы, который может запросить клиент
+	maxTasksLimit = 200
 )
 
 // TasksResponse - структура для ответа со списком задач
 type TasksResponse struct {
-	Tasks []*db.Task `json:"tasks"`
+	Tasks      []*db.Task `json:"tasks"`
+	NextCursor string     `json:"next_cursor,omitempty"`
+	Total      int        `json:"total"`
 }
 
 func getTaskListHandler(w http.ResponseWriter, r *http.Request) {
@@ -16,16 +26,123 @@ func getTaskListHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	search := r.URL.Query().Get("search")
-	tasks, err := db.Tasks(50, search)
+	now, err := clientNow(r)
+	if err != nil {
+		writeJSON(w, ErrorResponse{Error: err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	projectID, err := projectParam(r)
+	if err != nil {
+		writeJSON(w, ErrorResponse{Error: err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	statuses, err := statusParams(r)
+	if err != nil {
+		writeJSON(w, ErrorResponse{Error: err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	query := db.TaskQuery{
+		Search:    r.URL.Query().Get("search"),
+		Now:       now,
+		Tags:      tagParams(r),
+		ProjectID: projectID,
+		Sort:      r.URL.Query().Get("sort"),
+		Blocked:   r.URL.Query().Get("blocked"),
+		Statuses:  statuses,
+		Cursor:    r.URL.Query().Get("cursor"),
+	}
+
+	// Направление сортировки: order=asc (по умолчанию) или order=desc
+	switch r.URL.Query().Get("order") {
+	case "", "asc":
+	case "desc":
+		query.Desc = true
+	default:
+		writeJSON(w, ErrorResponse{Error: "Invalid order, expected asc or desc"}, http.StatusBadRequest)
+		return
+	}
+
+	var ok bool
+	if query.Limit, ok = limitParam(w, r); !ok {
+		return
+	}
+
+	if r.URL.Query().Get("view") == "board" {
+		writeBoard(w, query)
+		return
+	}
+
+	page, err := db.Tasks(query)
 	if err != nil {
-		writeJSON(w, ErrorResponse{Error: err.Error()}, http.StatusInternalServerError)
+		writeTasksError(w, err)
 		return
 	}
 
+	tasks := page.Tasks
 	if tasks == nil {
 		tasks = make([]*db.Task, 0)
 	}
 
-	writeJSON(w, TasksResponse{Tasks: tasks}, http.StatusOK)
+	writeJSON(w, TasksResponse{
+		Tasks:      tasks,
+		NextCursor: page.NextCursor,
+		Total:      page.Total,
+	}, http.StatusOK)
+}
+
+// writeBoard отвечает задачами, сгруппированными по статусам: по колонке
+// на каждый выбранный статус, без статусов - на все. Лимит действует
+// на каждую колонку, курсор не поддерживается.
+func writeBoard(w http.ResponseWriter, query db.TaskQuery) {
+	if query.Cursor != "" {
+		writeJSON(w, ErrorResponse{Error: "Cursor is not supported for board view"}, http.StatusBadRequest)
+		return
+	}
+
+	statuses := query.Statuses
+	if len(statuses) == 0 {
+		statuses = db.Statuses
+	}
+
+	board := BoardResponse{Columns: make([]BoardColumn, 0, len(statuses))}
+	for _, status := range statuses {
+		query.Statuses = []string{status}
+		page, err := db.Tasks(query)
+		if err != nil {
+			writeTasksError(w, err)
+			return
+		}
+		board.Columns = append(board.Columns, BoardColumn{Status: status, Tasks: page.Tasks, Total: page.Total})
+	}
+
+	writeJSON(w, board, http.StatusOK)
+}
+
+// limitParam читает размер страницы из параметра limit: по умолчанию
+// defaultTasksLimit, не больше maxTasksLimit.
+// При ошибке ответ клиенту уже записан и возвращается false.
+func limitParam(w http.ResponseWriter, r *http.Request) (int, bool) {
+	limitStr := r.URL.Query().Get("limit")
+	if limitStr == "" {
+		return defaultTasksLimit, true
+	}
+	limit, err := strconv.Atoi(limitStr)
+	if err != nil || limit <= 0 {
+		writeJSON(w, ErrorResponse{Error: "Invalid limit"}, http.StatusBadRequest)
+		return 0, false
+	}
+	return min(limit, maxTasksLimit), true
+}
+
+func writeTasksError(w http.ResponseWriter, err error) {
+	if errors.Is(err, db.ErrInvalidCursor) || errors.Is(err, db.ErrInvalidQuery) ||
+		errors.Is(err, db.ErrInvalidTag) || errors.Is(err, db.ErrInvalidSort) ||
+		errors.Is(err, db.ErrInvalidStatus) {
+		writeJSON(w, ErrorResponse{Error: err.Error()}, http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, ErrorResponse{Error: err.Error()}, http.StatusInternalServerError)
 }
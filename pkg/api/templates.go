@@ -0,0 +1,220 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go1f/pkg/db"
+)
+
+// TemplatesResponse - структура для ответа со списком шаблонов
+type TemplatesResponse struct {
+	Templates []*db.Template `json:"templates"`
+}
+
+// errInvalidTaskDate оборачивает ошибки проверки дат задачи, созданной
+// по шаблону или копированием
+var errInvalidTaskDate = errors.New("invalid task date")
+
+// anchorRequest - необязательное тело запроса на создание задачи по шаблону
+// или копии задачи. Anchor - опорная дата в формате YYYYMMDD.
+type anchorRequest struct {
+	Anchor string `json:"anchor"`
+}
+
+// templatesHandler - CRUD для шаблонов задач. GET с параметром id
+// возвращает один шаблон.
+func templatesHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		if r.URL.Query().Get("id") != "" {
+			id, ok := requireID(w, r)
+			if !ok {
+				return
+			}
+			template, err := db.GetTemplate(id)
+			if err != nil {
+				writeTemplateError(w, err)
+				return
+			}
+			writeJSON(w, template, http.StatusOK)
+			return
+		}
+
+		templates, err := db.Templates()
+		if err != nil {
+			writeJSON(w, ErrorResponse{Error: err.Error()}, http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, TemplatesResponse{Templates: templates}, http.StatusOK)
+
+	case http.MethodPost:
+		template, ok := decodeTemplate(w, r)
+		if !ok {
+			return
+		}
+
+		id, err := db.AddTemplate(template)
+		if err != nil {
+			writeTemplateError(w, err)
+			return
+		}
+		writeJSON(w, taskResponse{ID: id}, http.StatusOK)
+
+	case http.MethodPut:
+		template, ok := decodeTemplate(w, r)
+		if !ok {
+			return
+		}
+		if template.ID == 0 {
+			writeJSON(w, ErrorResponse{Error: "Template ID is required"}, http.StatusBadRequest)
+			return
+		}
+
+		if err := db.UpdateTemplate(template); err != nil {
+			writeTemplateError(w, err)
+			return
+		}
+		writeJSON(w, struct{}{}, http.StatusOK)
+
+	case http.MethodDelete:
+		id, ok := requireID(w, r)
+		if !ok {
+			return
+		}
+
+		if err := db.DeleteTemplate(id); err != nil {
+			writeTemplateError(w, err)
+			return
+		}
+		writeJSON(w, struct{}{}, http.StatusOK)
+
+	default:
+		writeJSON(w, ErrorResponse{Error: "Method not allowed"}, http.StatusMethodNotAllowed)
+	}
+}
+
+// decodeTemplate читает шаблон из тела запроса и проверяет правило повторения.
+// При ошибке ответ клиенту уже записан и возвращается false.
+func decodeTemplate(w http.ResponseWriter, r *http.Request) (*db.Template, bool) {
+	var template db.Template
+	if err := json.NewDecoder(r.Body).Decode(&template); err != nil {
+		writeJSON(w, ErrorResponse{Error: "Invalid JSON format"}, http.StatusBadRequest)
+		return nil, false
+	}
+
+	if template.Repeat != "" {
+		now := time.Now()
+		if _, err := NextDate(now, now.Format(dateFormat), template.Repeat); err != nil {
+			writeJSON(w, ErrorResponse{Error: err.Error()}, http.StatusBadRequest)
+			return nil, false
+		}
+	}
+	return &template, true
+}
+
+// templateInstantiateHandler создаёт задачу по шаблону. Даты задачи
+// отсчитываются от опорной даты, по умолчанию - от сегодняшней.
+func templateInstantiateHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, ErrorResponse{Error: "Method not allowed"}, http.StatusMethodNotAllowed)
+		return
+	}
+
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeJSON(w, ErrorResponse{Error: "Invalid ID format"}, http.StatusBadRequest)
+		return
+	}
+
+	anchor, ok := decodeAnchor(w, r)
+	if !ok {
+		return
+	}
+	date := time.Now()
+	if anchor != "" {
+		// Формат уже проверен в decodeAnchor
+		date, _ = time.Parse(dateFormat, anchor)
+	}
+
+	taskID, err := db.InstantiateTemplate(r.Context(), id, date, prepareNewTaskDate)
+	if err != nil {
+		writeTemplateError(w, err)
+		return
+	}
+	writeJSON(w, taskResponse{ID: taskID}, http.StatusOK)
+}
+
+// taskCloneHandler создаёт копию задачи. С опорной датой копия планируется
+// на неё, а срок сдвигается на столько же дней, без неё даты не меняются.
+// Дата в прошлом переносится так же, как при создании задачи по шаблону.
+func taskCloneHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, ErrorResponse{Error: "Method not allowed"}, http.StatusMethodNotAllowed)
+		return
+	}
+
+	id, ok := requireID(w, r)
+	if !ok {
+		return
+	}
+
+	anchor, ok := decodeAnchor(w, r)
+	if !ok {
+		return
+	}
+
+	cloneID, err := db.CloneTask(r.Context(), id, anchor, prepareNewTaskDate)
+	if err != nil {
+		writeTaskError(w, err)
+		return
+	}
+	writeJSON(w, taskResponse{ID: cloneID}, http.StatusOK)
+}
+
+// prepareNewTaskDate переносит дату задачи в прошлом так же, как /api/addtask
+func prepareNewTaskDate(task *db.Task) error {
+	if err := processTaskDate(task, time.Now()); err != nil {
+		return fmt.Errorf("%w: %v", errInvalidTaskDate, err)
+	}
+	return nil
+}
+
+// decodeAnchor читает необязательную опорную дату из тела запроса.
+// При ошибке ответ клиенту уже записан и возвращается false.
+func decodeAnchor(w http.ResponseWriter, r *http.Request) (string, bool) {
+	var req anchorRequest
+	if r.ContentLength > 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, ErrorResponse{Error: "Invalid JSON format"}, http.StatusBadRequest)
+			return "", false
+		}
+	}
+
+	if req.Anchor != "" {
+		if _, err := time.Parse(dateFormat, req.Anchor); err != nil {
+			writeJSON(w, ErrorResponse{Error: "Invalid anchor date format, use YYYYMMDD"}, http.StatusBadRequest)
+			return "", false
+		}
+	}
+	return req.Anchor, true
+}
+
+func writeTemplateError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, db.ErrTemplateNotFound):
+		writeJSON(w, ErrorResponse{Error: err.Error()}, http.StatusNotFound)
+	case errors.Is(err, db.ErrInvalidTemplate), errors.Is(err, errInvalidTaskDate),
+		errors.Is(err, db.ErrInvalidTag), errors.Is(err, db.ErrInvalidPriority),
+		errors.Is(err, db.ErrProjectNotFound):
+		writeJSON(w, ErrorResponse{Error: err.Error()}, http.StatusBadRequest)
+	case errors.Is(err, db.ErrTemplateExists), errors.Is(err, db.ErrProjectArchived):
+		writeJSON(w, ErrorResponse{Error: err.Error()}, http.StatusConflict)
+	default:
+		writeJSON(w, ErrorResponse{Error: err.Error()}, http.StatusInternalServerError)
+	}
+}
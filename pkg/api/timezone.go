@@ -0,0 +1,31 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	// База часовых поясов встраивается в бинарник: в образе alpine её нет
+	_ "time/tzdata"
+)
+
+// clientNow возвращает текущий момент в часовом поясе клиента, переданном
+// параметром tz или заголовком X-Timezone (например, Europe/Moscow).
+// Без них используются часы сервера.
+func clientNow(r *http.Request) (time.Time, error) {
+	tz := r.URL.Query().Get("tz")
+	if tz == "" {
+		tz = r.Header.Get("X-Timezone")
+	}
+
+	now := time.Now()
+	if tz == "" {
+		return now, nil
+	}
+
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return now, fmt.Errorf("unknown time zone %q", tz)
+	}
+	return now.In(loc), nil
+}
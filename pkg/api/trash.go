@@ -0,0 +1,64 @@
+package api
+
+import (
+	"net/http"
+
+	"go1f/pkg/db"
+)
+
+// trashHandler показывает содержимое корзины и окончательно удаляет задачи из неё.
+// Список разбивается на страницы параметрами limit и cursor, как /api/tasks.
+func trashHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		limit, ok := limitParam(w, r)
+		if !ok {
+			return
+		}
+
+		page, err := db.TrashedTasks(limit, r.URL.Query().Get("cursor"))
+		if err != nil {
+			writeTasksError(w, err)
+			return
+		}
+		writeJSON(w, TasksResponse{
+			Tasks:      page.Tasks,
+			NextCursor: page.NextCursor,
+			Total:      page.Total,
+		}, http.StatusOK)
+
+	case http.MethodDelete:
+		id, ok := requireID(w, r)
+		if !ok {
+			return
+		}
+		if err := db.PurgeTask(r.Context(), id); err != nil {
+			writeTaskError(w, err)
+			return
+		}
+		writeJSON(w, struct{}{}, http.StatusOK)
+
+	default:
+		writeJSON(w, ErrorResponse{Error: "Method not allowed"}, http.StatusMethodNotAllowed)
+	}
+}
+
+// trashRestoreHandler возвращает задачу из корзины
+func trashRestoreHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, ErrorResponse{Error: "Method not allowed"}, http.StatusMethodNotAllowed)
+		return
+	}
+
+	id, ok := requireID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := db.RestoreTask(r.Context(), id); err != nil {
+		writeTaskError(w, err)
+		return
+	}
+
+	writeJSON(w, struct{}{}, http.StatusOK)
+}
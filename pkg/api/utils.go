@@ -3,6 +3,7 @@ package api
 import (
 	"encoding/json"
 	"net/http"
+	"strconv"
 )
 
 func writeJSON(w http.ResponseWriter, data interface{}, statusCode int) {
@@ -10,3 +11,21 @@ func writeJSON(w http.ResponseWriter, data interface{}, statusCode int) {
 	w.WriteHeader(statusCode)
 	json.NewEncoder(w).Encode(data)
 }
+
+// requireID читает обязательный параметр id из запроса.
+// При ошибке ответ клиенту уже записан и возвращается false.
+func requireID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	idStr := r.URL.Query().Get("id")
+	if idStr == "" {
+		writeJSON(w, ErrorResponse{Error: "ID is required"}, http.StatusBadRequest)
+		return 0, false
+	}
+
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		writeJSON(w, ErrorResponse{Error: "Invalid ID format"}, http.StatusBadRequest)
+		return 0, false
+	}
+
+	return id, true
+}
@@ -11,6 +11,9 @@ import (
 
 var secretKey []byte
 
+// defaultSubject - имя пользователя в токене, если TODO_USER не задан
+const defaultSubject = "admin"
+
 func init() {
 	password := os.Getenv("TODO_PASSWORD")
 	if password != "" {
@@ -24,6 +27,11 @@ type Claims struct {
 	PasswordHash string `json:"pwd_hash"`
 }
 
+// Enabled сообщает, что задан пароль и запросы требуют токен
+func Enabled() bool {
+	return len(secretKey) > 0
+}
+
 func GenerateToken() (string, error) {
 	if len(secretKey) == 0 {
 		return "", nil
@@ -32,8 +40,14 @@ func GenerateToken() (string, error) {
 	password := os.Getenv("TODO_PASSWORD")
 	hash := sha256.Sum256([]byte(password))
 
+	subject := os.Getenv("TODO_USER")
+	if subject == "" {
+		subject = defaultSubject
+	}
+
 	claims := &Claims{
 		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   subject,
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(8 * time.Hour)),
 		},
 		PasswordHash: hex.EncodeToString(hash[:]),
@@ -64,3 +78,25 @@ func ValidateToken(tokenString string) (bool, error) {
 
 	return false, nil
 }
+
+// TokenSubject возвращает пользователя, которому выдан действительный токен
+func TokenSubject(tokenString string) (string, error) {
+	if len(secretKey) == 0 {
+		return "", nil
+	}
+
+	claims := &Claims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		return secretKey, nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	currentHash := sha256.Sum256([]byte(os.Getenv("TODO_PASSWORD")))
+	if !token.Valid || claims.PasswordHash != hex.EncodeToString(currentHash[:]) {
+		return "", jwt.ErrTokenInvalidClaims
+	}
+
+	return claims.Subject, nil
+}
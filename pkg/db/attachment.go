@@ -0,0 +1,292 @@
+package db
+
+import (
+	"bytes"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+)
+
+var (
+	// ErrAttachmentNotFound возвращается, если вложения с указанным ID нет
+	ErrAttachmentNotFound = errors.New("attachment not found")
+	// ErrAttachmentTooLarge возвращается, если файл больше TODO_ATTACH_MAX_SIZE
+	ErrAttachmentTooLarge = errors.New("attachment is too large")
+	// ErrAttachmentType возвращается для типа файла не из TODO_ATTACH_TYPES
+	ErrAttachmentType = errors.New("attachment type is not allowed")
+)
+
+// attachments - настройки хранения вложений, задаются в Init
+var attachments struct {
+	dir     string
+	maxSize int64
+	types   []string
+}
+
+// Attachment - метаданные файла, приложенного к задаче. Сам файл хранится
+// на диске в каталоге TODO_ATTACH_DIR под случайным именем.
+type Attachment struct {
+	ID        int64  `json:"id"`
+	TaskID    int64  `json:"task_id"`
+	Name      string `json:"name"`
+	MIMEType  string `json:"mime_type"`
+	Size      int64  `json:"size"`
+	CreatedAt string `json:"created_at"`
+	storedAs  string
+}
+
+// initAttachments создаёт каталог вложений и удаляет файлы, оставшиеся
+// после удаления задач
+func initAttachments(cfg Config) error {
+	attachments.dir = cfg.AttachDir
+	attachments.maxSize = cfg.AttachMaxSize
+	attachments.types = cfg.AttachTypes
+
+	if err := os.MkdirAll(attachments.dir, 0755); err != nil {
+		return fmt.Errorf("failed to create attachments directory: %w", err)
+	}
+	sweepAttachmentFiles()
+	return nil
+}
+
+// allowedType проверяет тип файла по списку, допускающему маски вида image/*
+func allowedType(mimeType string) bool {
+	for _, allowed := range attachments.types {
+		if allowed == mimeType {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(allowed, "/*"); ok && strings.HasPrefix(mimeType, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// cleanFileName оставляет от имени файла клиента только последний элемент
+// пути без управляющих символов
+func cleanFileName(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, name)
+	if name == "" || name == "." || name == "/" {
+		return "file"
+	}
+	return name
+}
+
+// AddAttachment сохраняет файл из r и прикрепляет его к задаче. Тип файла
+// определяется по содержимому, а не по заголовкам клиента.
+func AddAttachment(taskID int64, name string, r io.Reader) (*Attachment, error) {
+	exists, err := taskExists(DB, taskID)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrNotFound
+	}
+
+	// Первые 512 байт нужны http.DetectContentType
+	head := make([]byte, 512)
+	n, err := io.ReadFull(r, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil, fmt.Errorf("failed to read attachment: %w", err)
+	}
+	head = head[:n]
+
+	mimeType, _, err := mime.ParseMediaType(http.DetectContentType(head))
+	if err != nil || !allowedType(mimeType) {
+		return nil, fmt.Errorf("%w: %s", ErrAttachmentType, mimeType)
+	}
+
+	storedAs, err := randomFileName()
+	if err != nil {
+		return nil, err
+	}
+	path := filepath.Join(attachments.dir, storedAs)
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create attachment file: %w", err)
+	}
+
+	// Читаем на байт больше лимита, чтобы отличить файл ровно в лимит от большего
+	size, err := io.Copy(file, io.LimitReader(io.MultiReader(bytes.NewReader(head), r), attachments.maxSize+1))
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && size > attachments.maxSize {
+		err = ErrAttachmentTooLarge
+	}
+	if err != nil {
+		os.Remove(path)
+		if errors.Is(err, ErrAttachmentTooLarge) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to write attachment: %w", err)
+	}
+
+	a := &Attachment{
+		TaskID:    taskID,
+		Name:      cleanFileName(name),
+		MIMEType:  mimeType,
+		Size:      size,
+		CreatedAt: formatTimestamp(time.Now()),
+		storedAs:  storedAs,
+	}
+
+	// Задача могла быть удалена, пока загружался файл - тогда внешний ключ
+	// не даст вставить запись
+	res, err := DB.Exec(`INSERT INTO attachments (task_id, name, mime_type, size, stored_as, created_at) 
+	                     SELECT id, ?, ?, ?, ?, ? FROM scheduler WHERE id = ? AND deleted_at IS NULL`,
+		a.Name, a.MIMEType, a.Size, a.storedAs, a.CreatedAt, taskID)
+	if err == nil {
+		var n int64
+		if n, err = res.RowsAffected(); err == nil && n == 0 {
+			err = ErrNotFound
+		}
+	}
+	if err != nil {
+		os.Remove(path)
+		if errors.Is(err, ErrNotFound) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to insert attachment: %w", err)
+	}
+
+	a.ID, err = res.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get last insert ID: %w", err)
+	}
+	return a, nil
+}
+
+func randomFileName() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate file name: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// Attachments возвращает вложения задачи в порядке загрузки
+func Attachments(taskID int64) ([]*Attachment, error) {
+	exists, err := taskExists(DB, taskID)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrNotFound
+	}
+
+	rows, err := DB.Query(`SELECT id, task_id, name, mime_type, size, created_at 
+	                       FROM attachments WHERE task_id = ? ORDER BY id`, taskID)
+	if err != nil {
+		return nil, fmt.Errorf("database query error: %w", err)
+	}
+	defer rows.Close()
+
+	list := make([]*Attachment, 0)
+	for rows.Next() {
+		var a Attachment
+		if err := rows.Scan(&a.ID, &a.TaskID, &a.Name, &a.MIMEType, &a.Size, &a.CreatedAt); err != nil {
+			return nil, fmt.Errorf("row scan error: %w", err)
+		}
+		list = append(list, &a)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return list, nil
+}
+
+// OpenAttachment возвращает метаданные вложения и открытый файл.
+// Файл закрывает вызывающий.
+func OpenAttachment(id int64) (*Attachment, *os.File, error) {
+	var a Attachment
+	err := DB.QueryRow(`SELECT id, task_id, name, mime_type, size, created_at, stored_as 
+	                    FROM attachments WHERE id = ?`, id).
+		Scan(&a.ID, &a.TaskID, &a.Name, &a.MIMEType, &a.Size, &a.CreatedAt, &a.storedAs)
+	if err == sql.ErrNoRows {
+		return nil, nil, ErrAttachmentNotFound
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get attachment: %w", err)
+	}
+
+	file, err := os.Open(filepath.Join(attachments.dir, a.storedAs))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil, ErrAttachmentNotFound
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open attachment: %w", err)
+	}
+	return &a, file, nil
+}
+
+// DeleteAttachment удаляет вложение вместе с файлом
+func DeleteAttachment(id int64) error {
+	res, err := DB.Exec(`DELETE FROM attachments WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete attachment: %w", err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if n == 0 {
+		return ErrAttachmentNotFound
+	}
+
+	sweepAttachmentFiles()
+	return nil
+}
+
+// sweepAttachmentFiles удаляет с диска файлы удалённых вложений. Записи
+// о них добавляет триггер на attachments, в том числе при каскадном
+// удалении вместе с задачей, поэтому файлы удаляются только после
+// фиксации транзакции. Ошибки только записываются в лог: оставшиеся
+// файлы будут удалены при следующем вызове.
+func sweepAttachmentFiles() {
+	rows, err := DB.Query(`SELECT stored_as FROM attachment_files_deleted`)
+	if err != nil {
+		log.Printf("Attachment cleanup error: %v", err)
+		return
+	}
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err == nil {
+			names = append(names, name)
+		}
+	}
+	rows.Close()
+
+	for _, name := range names {
+		err := os.Remove(filepath.Join(attachments.dir, name))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			log.Printf("Attachment cleanup error: %v", err)
+			continue
+		}
+		if _, err := DB.Exec(`DELETE FROM attachment_files_deleted WHERE stored_as = ?`, name); err != nil {
+			log.Printf("Attachment cleanup error: %v", err)
+		}
+	}
+}
@@ -0,0 +1,268 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Действия, фиксируемые в журнале изменений
+const (
+	AuditInsert   = "insert"
+	AuditUpdate   = "update"
+	AuditDelete   = "delete"
+	AuditComplete = "complete"
+	AuditRestore  = "restore"
+	AuditPurge    = "purge"
+	AuditRevert   = "revert"
+	AuditMove     = "move"
+	AuditStatus   = "status"
+	AuditReorder  = "reorder"
+)
+
+// systemActor записывается в журнал для изменений без пользователя,
+// например при фоновой очистке корзины
+const systemActor = "system"
+
+type actorKey struct{}
+
+// WithActor сохраняет в контексте автора изменений для журнала
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+func actorFrom(ctx context.Context) string {
+	if actor, ok := ctx.Value(actorKey{}).(string); ok && actor != "" {
+		return actor
+	}
+	return systemActor
+}
+
+// AuditEntry - запись журнала изменений задачи. Before и After содержат
+// JSON-снимки задачи до и после изменения, null - задачи не было.
+type AuditEntry struct {
+	ID        int64           `json:"id"`
+	TaskID    int64           `json:"task_id"`
+	Action    string          `json:"action"`
+	Actor     string          `json:"actor"`
+	Before    json.RawMessage `json:"before"`
+	After     json.RawMessage `json:"after"`
+	CreatedAt string          `json:"created_at"`
+}
+
+// AuditFilter задаёт выборку из журнала изменений.
+// Нулевые значения полей означают отсутствие ограничения.
+type AuditFilter struct {
+	TaskID int64
+	From   time.Time
+	To     time.Time
+	Limit  int
+}
+
+// snapshotTask возвращает JSON-снимок задачи, включая находящуюся в корзине.
+// Снимок содержит поля задачи и метки, но не чек-лист. Если задачи нет,
+// возвращается nil.
+func snapshotTask(q querier, id int64) ([]byte, error) {
+	var task Task
+	var deletedAt sql.NullString
+	err := q.QueryRow(`SELECT id, date, due, title, comment, repeat, project_id, priority, 
+	                          status, COALESCE(completed_at, ''), rank, deleted_at 
+	                   FROM scheduler 
+	                   WHERE id = ?`, id).Scan(
+		&task.ID, &task.Date, &task.Due, &task.Title, &task.Comment, &task.Repeat,
+		&task.ProjectID, &task.Priority, &task.Status, &task.CompletedAt, &task.Rank, &deletedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read task snapshot: %w", err)
+	}
+	task.DeletedAt = deletedAt.String
+
+	if err := loadTags(q, []*Task{&task}); err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(task)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode task snapshot: %w", err)
+	}
+	return data, nil
+}
+
+// recordChange дописывает изменение задачи в журнал. Состояние после
+// изменения читается в той же транзакции, что и само изменение.
+func recordChange(ctx context.Context, q querier, id int64, action string, before []byte) error {
+	after, err := snapshotTask(q, id)
+	if err != nil {
+		return err
+	}
+
+	_, err = q.Exec(`INSERT INTO audit_log (task_id, action, actor, before_json, after_json, created_at) 
+	                 VALUES (?, ?, ?, ?, ?, ?)`,
+		id, action, actorFrom(ctx), nullJSON(before), nullJSON(after), formatTimestamp(time.Now()))
+	if err != nil {
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+	return nil
+}
+
+func nullJSON(data []byte) sql.NullString {
+	return sql.NullString{String: string(data), Valid: data != nil}
+}
+
+// AuditLog возвращает записи журнала изменений, начиная с самых свежих
+func AuditLog(filter AuditFilter) ([]*AuditEntry, error) {
+	var conds []string
+	var args []interface{}
+
+	if filter.TaskID != 0 {
+		conds = append(conds, "task_id = ?")
+		args = append(args, filter.TaskID)
+	}
+	if !filter.From.IsZero() {
+		conds = append(conds, "created_at >= ?")
+		args = append(args, formatTimestamp(filter.From))
+	}
+	if !filter.To.IsZero() {
+		conds = append(conds, "created_at < ?")
+		args = append(args, formatTimestamp(filter.To))
+	}
+
+	query := `SELECT id, task_id, action, actor, before_json, after_json, created_at 
+	          FROM audit_log`
+	if len(conds) > 0 {
+		query += " WHERE " + strings.Join(conds, " AND ")
+	}
+	query += " ORDER BY id DESC LIMIT ?"
+	args = append(args, filter.Limit)
+
+	rows, err := DB.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("database query error: %w", err)
+	}
+	defer rows.Close()
+
+	entries := make([]*AuditEntry, 0)
+	for rows.Next() {
+		var e AuditEntry
+		var before, after sql.NullString
+		if err := rows.Scan(&e.ID, &e.TaskID, &e.Action, &e.Actor,
+			&before, &after, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("row scan error: %w", err)
+		}
+		e.Before = rawJSON(before)
+		e.After = rawJSON(after)
+		entries = append(entries, &e)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	return entries, nil
+}
+
+func rawJSON(s sql.NullString) json.RawMessage {
+	if !s.Valid {
+		return json.RawMessage("null")
+	}
+	return json.RawMessage(s.String)
+}
+
+// RevertAudit возвращает задачу в состояние, в котором она была до
+// изменения из записи журнала auditID. Если до изменения задачи не было,
+// задача перемещается в корзину. Сам откат также попадает в журнал.
+func RevertAudit(ctx context.Context, auditID int64) (int64, error) {
+	var taskID int64
+	err := inTx(func(tx *sql.Tx) error {
+		var snapshot sql.NullString
+		err := tx.QueryRow(`SELECT task_id, before_json FROM audit_log WHERE id = ?`, auditID).
+			Scan(&taskID, &snapshot)
+		if err == sql.ErrNoRows {
+			return ErrNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read audit entry: %w", err)
+		}
+
+		current, err := snapshotTask(tx, taskID)
+		if err != nil {
+			return err
+		}
+
+		if !snapshot.Valid {
+			if current == nil {
+				return nil
+			}
+			_, err = tx.Exec(`UPDATE scheduler 
+			                  SET deleted_at = COALESCE(deleted_at, ?), version = version + 1 
+			                  WHERE id = ?`, formatTimestamp(time.Now()), taskID)
+		} else {
+			var task Task
+			if err := json.Unmarshal([]byte(snapshot.String), &task); err != nil {
+				return fmt.Errorf("failed to decode audit snapshot: %w", err)
+			}
+			deletedAt := sql.NullString{String: task.DeletedAt, Valid: task.DeletedAt != ""}
+			// Проект из снимка мог быть удалён - тогда задача вернётся во входящие.
+			// В снимках до появления проектов, приоритетов и статусов этих
+			// полей нет - их не меняем. Ранг из снимка возвращает задачу
+			// на прежнее место в дне, без него ранг сбрасывается при смене даты.
+			var projectID int64
+			projectID, err = revertProject(tx, task.ProjectID)
+			if err != nil {
+				return err
+			}
+			completedAt := sql.NullString{String: task.CompletedAt, Valid: task.CompletedAt != ""}
+			_, err = tx.Exec(`INSERT INTO scheduler (id, date, due, title, comment, repeat, project_id, 
+			                                         priority, status, completed_at, rank, deleted_at) 
+			                  VALUES (?, ?, ?, ?, ?, ?, COALESCE(NULLIF(?, 0), 1), COALESCE(NULLIF(?, 0), 4), 
+			                          COALESCE(NULLIF(?, ''), 'todo'), ?, ?, ?) 
+			                  ON CONFLICT(id) DO UPDATE SET 
+			                      rank = CASE WHEN excluded.rank <> '' THEN excluded.rank 
+			                                  WHEN date = excluded.date THEN rank ELSE '' END, 
+			                      date = excluded.date, due = excluded.due, title = excluded.title, 
+			                      comment = excluded.comment, repeat = excluded.repeat, 
+			                      project_id = COALESCE(NULLIF(?, 0), project_id), 
+			                      priority = COALESCE(NULLIF(?, 0), priority), 
+			                      status = COALESCE(NULLIF(?, ''), status), 
+			                      completed_at = CASE WHEN ? = '' THEN completed_at ELSE excluded.completed_at END, 
+			                      deleted_at = excluded.deleted_at, version = version + 1`,
+				taskID, task.Date, task.Due, task.Title, task.Comment, task.Repeat, projectID, task.Priority,
+				task.Status, completedAt, task.Rank, deletedAt,
+				projectID, task.Priority, task.Status, task.Status)
+			// В снимках, сделанных до появления меток, их нет - метки не трогаем
+			if err == nil && task.Tags != nil {
+				err = setTaskTags(tx, taskID, task.Tags)
+			}
+			if err == nil {
+				err = setTaskLinks(tx, taskID, task.Comment)
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("failed to revert task: %w", err)
+		}
+
+		return recordChange(ctx, tx, taskID, AuditRevert, current)
+	})
+	return taskID, err
+}
+
+// revertProject возвращает проект для восстановления задачи из снимка
+func revertProject(q querier, projectID int64) (int64, error) {
+	if projectID == 0 {
+		return 0, nil
+	}
+	var exists bool
+	err := q.QueryRow(`SELECT EXISTS(SELECT 1 FROM projects WHERE id = ?)`, projectID).Scan(&exists)
+	if err != nil {
+		return 0, fmt.Errorf("failed to check project: %w", err)
+	}
+	if !exists {
+		return InboxProjectID, nil
+	}
+	return projectID, nil
+}
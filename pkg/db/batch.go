@@ -0,0 +1,264 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Действия пакетной операции
+const (
+	BatchAdd      = "add"
+	BatchUpdate   = "update"
+	BatchDone     = "done"
+	BatchDelete   = "delete"
+	BatchMoveDate = "move_date"
+)
+
+// MaxBatchSize ограничивает число действий в пакете, включая задачи,
+// найденные поиском
+const MaxBatchSize = 1000
+
+var (
+	// ErrInvalidBatch возвращается для пустого, слишком большого пакета
+	// или неизвестного действия
+	ErrInvalidBatch = errors.New("invalid batch")
+	// ErrBatchFailed возвращается, если хотя бы одно действие пакета не выполнено.
+	// Пакет в этом случае не применяется целиком.
+	ErrBatchFailed = errors.New("batch was not applied")
+)
+
+// errBatchRollback откатывает транзакцию пакета без ошибки для вызывающего
+var errBatchRollback = errors.New("batch rollback")
+
+// BatchOp - одно действие пакета. Для add и update задача передаётся в Task,
+// для остальных действий - ID задачи. Update меняет поля так же, как
+// UpdateTask: срок без Task.DueSet остаётся прежним. Date - новая дата
+// для move_date, Note и Force - как при выполнении задачи через CompleteTask.
+type BatchOp struct {
+	Action string `json:"action"`
+	ID     int64  `json:"id,omitempty"`
+	Task   *Task  `json:"task,omitempty"`
+	Date   string `json:"date,omitempty"`
+	Note   string `json:"note,omitempty"`
+	Force  bool   `json:"force,omitempty"`
+}
+
+// BatchSearch применяет действие Op ко всем задачам, найденным запросом Query
+type BatchSearch struct {
+	Query TaskQuery
+	Op    BatchOp
+}
+
+// BatchResult - результат одного действия пакета. Index - номер действия
+// в запросе, для найденных поиском задач - номер задачи в выборке.
+type BatchResult struct {
+	Index  int    `json:"index"`
+	Action string `json:"action"`
+	ID     int64  `json:"id,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// BatchRequest - пакет действий над задачами
+type BatchRequest struct {
+	Ops []BatchOp
+	// Search выполняется после Ops, nil - без поиска
+	Search *BatchSearch
+	// DryRun проверяет пакет, не применяя изменений
+	DryRun bool
+	// NextDate вычисляет дату следующего повторения для действия done
+	NextDate func(now time.Time, date, repeat string) (string, error)
+	// PrepareTask проверяет и дополняет задачу действия add или update
+	// перед записью, nil - без проверки
+	PrepareTask func(action string, task *Task) error
+}
+
+// Batch выполняет действия req.Ops, а затем действие req.Search для каждой
+// найденной задачи, в одной транзакции. Каждое действие выполняется, даже
+// если предыдущие завершились ошибкой, чтобы отчёт содержал все ошибки, но
+// изменения применяются, только если выполнены все действия - иначе
+// возвращается ErrBatchFailed. При req.DryRun изменения откатываются всегда.
+func Batch(ctx context.Context, req BatchRequest) ([]BatchResult, error) {
+	ops, search := req.Ops, req.Search
+	var searchQuery *listQuery
+	if search != nil {
+		switch search.Op.Action {
+		case BatchDone, BatchDelete, BatchMoveDate:
+		default:
+			return nil, fmt.Errorf("%w: search supports only done, delete and move_date", ErrInvalidBatch)
+		}
+
+		now := search.Query.Now
+		if now.IsZero() {
+			now = time.Now()
+		}
+		// Запрос строится до начала транзакции: разбор фильтра project
+		// читает проекты через общее подключение
+		var err error
+		searchQuery, err = newListQuery(search.Query, now)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if search == nil && len(ops) == 0 {
+		return nil, fmt.Errorf("%w: no actions", ErrInvalidBatch)
+	}
+
+	var results []BatchResult
+	failed := false
+	err := inTx(func(tx *sql.Tx) error {
+		all := append([]BatchOp{}, ops...)
+		if search != nil {
+			// Лишняя строка показывает, что пакет превышает MaxBatchSize
+			ids, err := searchQuery.ids(tx, MaxBatchSize+1)
+			if err != nil {
+				return err
+			}
+			for _, id := range ids {
+				op := search.Op
+				op.ID = id
+				all = append(all, op)
+			}
+		}
+		if len(all) > MaxBatchSize {
+			return fmt.Errorf("%w: more than %d actions", ErrInvalidBatch, MaxBatchSize)
+		}
+
+		results = make([]BatchResult, len(all))
+		for i, op := range all {
+			index := i
+			if i >= len(ops) {
+				index = i - len(ops)
+			}
+			results[i] = BatchResult{Index: index, Action: op.Action, ID: op.ID}
+
+			id, err := runBatchOp(ctx, tx, op, req)
+			if err != nil {
+				results[i].Error = err.Error()
+				failed = true
+				continue
+			}
+			results[i].ID = id
+		}
+
+		if failed || req.DryRun {
+			return errBatchRollback
+		}
+		return nil
+	})
+	if err != nil && err != errBatchRollback {
+		return nil, err
+	}
+	if failed {
+		return results, ErrBatchFailed
+	}
+	return results, nil
+}
+
+// runBatchOp выполняет действие пакета в точке сохранения: если действие
+// не удалось, его частичные изменения откатываются, а остальные остаются.
+// Возвращает ID задачи, для add - добавленной.
+func runBatchOp(ctx context.Context, tx *sql.Tx, op BatchOp, req BatchRequest) (int64, error) {
+	if _, err := tx.Exec(`SAVEPOINT batch_op`); err != nil {
+		return 0, fmt.Errorf("failed to create savepoint: %w", err)
+	}
+
+	id, err := applyBatchOp(ctx, tx, op, req)
+	if err != nil {
+		if _, rbErr := tx.Exec(`ROLLBACK TO batch_op`); rbErr != nil {
+			return 0, fmt.Errorf("failed to roll back to savepoint: %w", rbErr)
+		}
+	}
+	if _, relErr := tx.Exec(`RELEASE batch_op`); relErr != nil {
+		return 0, fmt.Errorf("failed to release savepoint: %w", relErr)
+	}
+	return id, err
+}
+
+func applyBatchOp(ctx context.Context, tx *sql.Tx, op BatchOp, req BatchRequest) (int64, error) {
+	switch op.Action {
+	case BatchAdd, BatchUpdate:
+		if op.Task == nil {
+			return 0, fmt.Errorf("%w: task is required", ErrInvalidBatch)
+		}
+		if op.Action == BatchUpdate && op.Task.ID == 0 {
+			return 0, fmt.Errorf("%w: task ID is required", ErrInvalidBatch)
+		}
+		if req.PrepareTask != nil {
+			if err := req.PrepareTask(op.Action, op.Task); err != nil {
+				return 0, err
+			}
+		}
+		if op.Action == BatchAdd {
+			return addTask(ctx, tx, op.Task)
+		}
+		return op.Task.ID, updateTask(ctx, tx, op.Task)
+	}
+
+	if op.ID == 0 {
+		return 0, fmt.Errorf("%w: ID is required", ErrInvalidBatch)
+	}
+
+	switch op.Action {
+	case BatchDone:
+		return op.ID, completeTask(ctx, tx, op.ID, CompleteOptions{
+			Note:     op.Note,
+			Force:    op.Force,
+			NextDate: req.NextDate,
+		})
+
+	case BatchDelete:
+		exists, err := taskExists(tx, op.ID)
+		if err != nil {
+			return 0, err
+		}
+		if !exists {
+			return 0, ErrNotFound
+		}
+		return op.ID, deleteTask(ctx, tx, op.ID, 0)
+
+	case BatchMoveDate:
+		if _, err := time.Parse(storageDateFormat, op.Date); err != nil {
+			return 0, fmt.Errorf("%w: date must be in YYYYMMDD format", ErrInvalidBatch)
+		}
+		return op.ID, moveTaskDate(ctx, tx, op.ID, op.Date)
+	}
+
+	return 0, fmt.Errorf("%w: unknown action %q", ErrInvalidBatch, op.Action)
+}
+
+// moveTaskDate переносит задачу на другую дату. Срок выполнения не меняется,
+// ручной порядок сбрасывается.
+func moveTaskDate(ctx context.Context, tx *sql.Tx, id int64, date string) error {
+	before, err := snapshotTask(tx, id)
+	if err != nil {
+		return err
+	}
+
+	res, err := tx.Exec(`UPDATE scheduler 
+	                     SET rank = '', date = ?, version = version + 1 
+	                     WHERE id = ? AND deleted_at IS NULL AND date <> ?`, date, id, date)
+	if err != nil {
+		return fmt.Errorf("failed to move task date: %w", err)
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		// Задача уже на этой дате или её нет
+		exists, err := taskExists(tx, id)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return ErrNotFound
+		}
+		return nil
+	}
+
+	return recordChange(ctx, tx, id, AuditUpdate, before)
+}
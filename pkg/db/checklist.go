@@ -0,0 +1,237 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+const maxChecklistTitleLength = 255
+
+var (
+	// ErrInvalidChecklistItem возвращается для пустого или слишком длинного пункта
+	ErrInvalidChecklistItem = errors.New("invalid checklist item title")
+	// ErrChecklistItemNotFound возвращается, если пункта с указанным ID нет
+	ErrChecklistItemNotFound = errors.New("checklist item not found")
+)
+
+// ChecklistItem - пункт чек-листа задачи. Position - порядковый номер
+// пункта в чек-листе, начиная с нуля. Чек-лист не входит в снимок задачи,
+// поэтому изменения пунктов не попадают в журнал изменений и не
+// откатываются через него.
+type ChecklistItem struct {
+	ID       int64  `json:"id"`
+	TaskID   int64  `json:"task_id"`
+	Title    string `json:"title"`
+	Done     bool   `json:"done"`
+	Position int    `json:"position"`
+}
+
+// ChecklistProgress - число выполненных пунктов чек-листа задачи
+type ChecklistProgress struct {
+	Done  int `json:"done"`
+	Total int `json:"total"`
+}
+
+func normalizeChecklistTitle(title string) (string, error) {
+	title = strings.TrimSpace(title)
+	if title == "" || utf8.RuneCountInString(title) > maxChecklistTitleLength {
+		return "", ErrInvalidChecklistItem
+	}
+	return title, nil
+}
+
+// Checklist возвращает пункты чек-листа задачи по порядку
+func Checklist(taskID int64) ([]*ChecklistItem, error) {
+	exists, err := taskExists(DB, taskID)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrNotFound
+	}
+
+	items, err := checklistItems(DB, taskID)
+	if err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+func checklistItems(q querier, taskID int64) ([]*ChecklistItem, error) {
+	rows, err := q.Query(`SELECT id, task_id, title, done, position 
+	                      FROM checklist_items 
+	                      WHERE task_id = ? 
+	                      ORDER BY position, id`, taskID)
+	if err != nil {
+		return nil, fmt.Errorf("database query error: %w", err)
+	}
+	defer rows.Close()
+
+	items := make([]*ChecklistItem, 0)
+	for rows.Next() {
+		var item ChecklistItem
+		if err := rows.Scan(&item.ID, &item.TaskID, &item.Title, &item.Done, &item.Position); err != nil {
+			return nil, fmt.Errorf("row scan error: %w", err)
+		}
+		items = append(items, &item)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return items, nil
+}
+
+// AddChecklistItem добавляет пункт в конец чек-листа задачи
+func AddChecklistItem(item *ChecklistItem) (int64, error) {
+	title, err := normalizeChecklistTitle(item.Title)
+	if err != nil {
+		return 0, err
+	}
+	item.Title = title
+
+	var id int64
+	err = inTx(func(tx *sql.Tx) error {
+		exists, err := taskExists(tx, item.TaskID)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return ErrNotFound
+		}
+
+		res, err := tx.Exec(`INSERT INTO checklist_items (task_id, title, done, position) 
+		                     SELECT ?, ?, ?, COALESCE(MAX(position) + 1, 0) 
+		                     FROM checklist_items WHERE task_id = ?`,
+			item.TaskID, item.Title, item.Done, item.TaskID)
+		if err != nil {
+			return fmt.Errorf("failed to insert checklist item: %w", err)
+		}
+
+		id, err = res.LastInsertId()
+		if err != nil {
+			return fmt.Errorf("failed to get last insert ID: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+// UpdateChecklistItem меняет название и отметку пункта. Если move,
+// пункт также переставляется на позицию item.Position, а остальные
+// пункты сдвигаются.
+func UpdateChecklistItem(item *ChecklistItem, move bool) error {
+	title, err := normalizeChecklistTitle(item.Title)
+	if err != nil {
+		return err
+	}
+	item.Title = title
+
+	return inTx(func(tx *sql.Tx) error {
+		err := tx.QueryRow(`UPDATE checklist_items SET title = ?, done = ? WHERE id = ? 
+		                    RETURNING task_id`, item.Title, item.Done, item.ID).Scan(&item.TaskID)
+		if err == sql.ErrNoRows {
+			return ErrChecklistItemNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("failed to update checklist item: %w", err)
+		}
+
+		if !move {
+			return nil
+		}
+
+		items, err := checklistItems(tx, item.TaskID)
+		if err != nil {
+			return err
+		}
+
+		// Порядок без переставляемого пункта, затем вставка на новую позицию
+		order := make([]int64, 0, len(items))
+		for _, other := range items {
+			if other.ID != item.ID {
+				order = append(order, other.ID)
+			}
+		}
+		pos := min(max(item.Position, 0), len(order))
+		order = append(order[:pos], append([]int64{item.ID}, order[pos:]...)...)
+
+		for i, id := range order {
+			if _, err := tx.Exec(`UPDATE checklist_items SET position = ? WHERE id = ?`, i, id); err != nil {
+				return fmt.Errorf("failed to reorder checklist: %w", err)
+			}
+		}
+		item.Position = pos
+		return nil
+	})
+}
+
+// DeleteChecklistItem удаляет пункт чек-листа
+func DeleteChecklistItem(id int64) error {
+	res, err := DB.Exec(`DELETE FROM checklist_items WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete checklist item: %w", err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if n == 0 {
+		return ErrChecklistItemNotFound
+	}
+	return nil
+}
+
+// resetChecklist снимает отметки со всех пунктов чек-листа задачи
+func resetChecklist(q querier, taskID int64) error {
+	if _, err := q.Exec(`UPDATE checklist_items SET done = 0 WHERE task_id = ?`, taskID); err != nil {
+		return fmt.Errorf("failed to reset checklist: %w", err)
+	}
+	return nil
+}
+
+// loadChecklistProgress заполняет прогресс чек-листа у списка задач одним
+// запросом. У задач без пунктов прогресс остаётся пустым.
+func loadChecklistProgress(q querier, tasks []*Task) error {
+	if len(tasks) == 0 {
+		return nil
+	}
+
+	byID := make(map[int64]*Task, len(tasks))
+	placeholders := make([]string, len(tasks))
+	args := make([]interface{}, len(tasks))
+	for i, task := range tasks {
+		byID[task.ID] = task
+		placeholders[i] = "?"
+		args[i] = task.ID
+	}
+
+	rows, err := q.Query(`SELECT task_id, SUM(done), COUNT(*) 
+	                      FROM checklist_items 
+	                      WHERE task_id IN (`+strings.Join(placeholders, ", ")+`) 
+	                      GROUP BY task_id`, args...)
+	if err != nil {
+		return fmt.Errorf("failed to load checklist progress: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var taskID int64
+		var progress ChecklistProgress
+		if err := rows.Scan(&taskID, &progress.Done, &progress.Total); err != nil {
+			return fmt.Errorf("row scan error: %w", err)
+		}
+		if task, ok := byID[taskID]; ok {
+			task.Checklist = &progress
+		}
+	}
+	return rows.Err()
+}
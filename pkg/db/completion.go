@@ -0,0 +1,83 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// timestampFormat - формат хранения моментов времени. Время хранится в UTC,
+// поэтому строки сравниваются и сортируются так же, как сами моменты.
+const timestampFormat = time.RFC3339
+
+// Completion - запись истории о выполнении задачи
+type Completion struct {
+	ID            int64  `json:"id"`
+	TaskID        int64  `json:"task_id"`
+	Title         string `json:"title"`
+	ScheduledDate string `json:"scheduled_date"`
+	CompletedAt   string `json:"completed_at"`
+	Note          string `json:"note"`
+}
+
+// HistoryFilter задаёт выборку из истории выполнения.
+// Нулевые значения полей означают отсутствие ограничения.
+type HistoryFilter struct {
+	TaskID int64
+	From   time.Time
+	To     time.Time
+	Limit  int
+}
+
+func formatTimestamp(t time.Time) string {
+	return t.UTC().Format(timestampFormat)
+}
+
+// History возвращает записи о выполнении задач, начиная с самых свежих
+func History(filter HistoryFilter) ([]*Completion, error) {
+	var conds []string
+	var args []interface{}
+
+	if filter.TaskID != 0 {
+		conds = append(conds, "task_id = ?")
+		args = append(args, filter.TaskID)
+	}
+	if !filter.From.IsZero() {
+		conds = append(conds, "completed_at >= ?")
+		args = append(args, formatTimestamp(filter.From))
+	}
+	if !filter.To.IsZero() {
+		conds = append(conds, "completed_at < ?")
+		args = append(args, formatTimestamp(filter.To))
+	}
+
+	query := `SELECT id, task_id, title, scheduled_date, completed_at, note 
+	          FROM completions`
+	if len(conds) > 0 {
+		query += " WHERE " + strings.Join(conds, " AND ")
+	}
+	query += " ORDER BY completed_at DESC, id DESC LIMIT ?"
+	args = append(args, filter.Limit)
+
+	rows, err := DB.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("database query error: %w", err)
+	}
+	defer rows.Close()
+
+	completions := make([]*Completion, 0)
+	for rows.Next() {
+		var c Completion
+		if err := rows.Scan(&c.ID, &c.TaskID, &c.Title, &c.ScheduledDate,
+			&c.CompletedAt, &c.Note); err != nil {
+			return nil, fmt.Errorf("row scan error: %w", err)
+		}
+		completions = append(completions, &c)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	return completions, nil
+}
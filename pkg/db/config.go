@@ -0,0 +1,144 @@
+package db
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultJournalMode  = "WAL"
+	defaultSynchronous  = "NORMAL"
+	defaultBusyTimeout  = 5 * time.Second
+	defaultMaxOpenConns = 8
+	defaultMaxIdleConns = 4
+	defaultConnIdleTime = 5 * time.Minute
+	defaultTrashDays    = 30
+	// defaultAttachMaxSize - 10 МиБ
+	defaultAttachMaxSize = 10 << 20
+	defaultAttachTypes   = "image/*,application/pdf,text/plain"
+	// defaultIdempotencyTTL - сколько хранятся ключи идемпотентности
+	defaultIdempotencyTTL = 24 * time.Hour
+)
+
+// Config описывает параметры подключения к SQLite
+type Config struct {
+	File         string
+	JournalMode  string
+	Synchronous  string
+	BusyTimeout  time.Duration
+	MaxOpenConns int
+	MaxIdleConns int
+	ConnIdleTime time.Duration
+	// TrashDays - сколько дней задачи хранятся в корзине, 0 отключает очистку
+	TrashDays int
+	// AttachDir - каталог файлов вложений, по умолчанию рядом с базой
+	AttachDir string
+	// AttachMaxSize - максимальный размер вложения в байтах
+	AttachMaxSize int64
+	// AttachTypes - допустимые MIME-типы вложений, допускаются маски image/*
+	AttachTypes []string
+	// IdempotencyTTL - сколько хранится ответ на запрос с ключом
+	// идемпотентности, 0 отключает ключи
+	IdempotencyTTL time.Duration
+}
+
+// loadConfig читает настройки базы данных из переменных окружения
+func loadConfig() (Config, error) {
+	cfg := Config{
+		File:         getDBFilePath(),
+		JournalMode:  envString("TODO_DB_JOURNAL_MODE", defaultJournalMode),
+		Synchronous:  envString("TODO_DB_SYNCHRONOUS", defaultSynchronous),
+		BusyTimeout:  defaultBusyTimeout,
+		MaxOpenConns: defaultMaxOpenConns,
+		MaxIdleConns: defaultMaxIdleConns,
+		ConnIdleTime: defaultConnIdleTime,
+		TrashDays:    defaultTrashDays,
+	}
+	cfg.AttachDir = envString("TODO_ATTACH_DIR", filepath.Join(filepath.Dir(cfg.File), "attachments"))
+	for _, t := range strings.Split(envString("TODO_ATTACH_TYPES", defaultAttachTypes), ",") {
+		if t = strings.ToLower(strings.TrimSpace(t)); t != "" {
+			cfg.AttachTypes = append(cfg.AttachTypes, t)
+		}
+	}
+
+	var err error
+	if cfg.BusyTimeout, err = envDuration("TODO_DB_BUSY_TIMEOUT", cfg.BusyTimeout); err != nil {
+		return cfg, err
+	}
+	if cfg.ConnIdleTime, err = envDuration("TODO_DB_CONN_IDLE_TIME", cfg.ConnIdleTime); err != nil {
+		return cfg, err
+	}
+	if cfg.MaxOpenConns, err = envInt("TODO_DB_MAX_OPEN_CONNS", cfg.MaxOpenConns); err != nil {
+		return cfg, err
+	}
+	if cfg.MaxIdleConns, err = envInt("TODO_DB_MAX_IDLE_CONNS", cfg.MaxIdleConns); err != nil {
+		return cfg, err
+	}
+	if cfg.TrashDays, err = envInt("TODO_TRASH_RETENTION_DAYS", cfg.TrashDays); err != nil {
+		return cfg, err
+	}
+	if cfg.IdempotencyTTL, err = envDuration("TODO_IDEMPOTENCY_TTL", defaultIdempotencyTTL); err != nil {
+		return cfg, err
+	}
+	maxSize, err := envInt("TODO_ATTACH_MAX_SIZE", defaultAttachMaxSize)
+	if err != nil {
+		return cfg, err
+	}
+	cfg.AttachMaxSize = int64(maxSize)
+
+	return cfg, nil
+}
+
+// DSN собирает строку подключения с прагмами, которые драйвер
+// применяет к каждому новому соединению пула
+func (c Config) DSN() string {
+	q := url.Values{}
+	q.Add("_pragma", fmt.Sprintf("busy_timeout(%d)", c.BusyTimeout.Milliseconds()))
+	q.Add("_pragma", "journal_mode("+strings.ToUpper(c.JournalMode)+")")
+	q.Add("_pragma", "synchronous("+strings.ToUpper(c.Synchronous)+")")
+	q.Add("_pragma", "foreign_keys(1)")
+	// Пишущие транзакции сразу берут блокировку, чтобы не получать
+	// SQLITE_BUSY при повышении уровня блокировки посреди транзакции
+	q.Set("_txlock", "immediate")
+	return "file:" + c.File + "?" + q.Encode()
+}
+
+func envString(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
+func envInt(key string, def int) (int, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid %s value %q", key, v)
+	}
+	return n, nil
+}
+
+// envDuration принимает как длительность Go (5s, 250ms), так и число миллисекунд
+func envDuration(key string, def time.Duration) (time.Duration, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		return def, nil
+	}
+	if ms, err := strconv.Atoi(v); err == nil && ms >= 0 {
+		return time.Duration(ms) * time.Millisecond, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid %s value %q", key, v)
+	}
+	return d, nil
+}
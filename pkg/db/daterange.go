@@ -0,0 +1,138 @@
+package db
+
+import (
+	"slices"
+	"strings"
+	"time"
+)
+
+// isoDateFormat - дата в формате ISO 8601
+const isoDateFormat = "2006-01-02"
+
+// dateRange - диапазон дат задач в формате хранения, границы включаются
+type dateRange struct {
+	from string
+	to   string
+}
+
+// relativeDate - относительное обозначение периода и способ вычислить
+// его границы от текущего дня
+type relativeDate struct {
+	names   []string
+	resolve func(today time.Time) (from, to time.Time)
+}
+
+var relativeDates = []relativeDate{
+	{[]string{"today", "сегодня"}, dayOffset(0)},
+	{[]string{"tomorrow", "завтра"}, dayOffset(1)},
+	{[]string{"yesterday", "вчера"}, dayOffset(-1)},
+	{[]string{"this week", "эта неделя", "на этой неделе", "текущая неделя"}, weekOffset(0)},
+	{[]string{"next week", "следующая неделя", "на следующей неделе"}, weekOffset(1)},
+	{[]string{"last week", "прошлая неделя", "на прошлой неделе"}, weekOffset(-1)},
+	{[]string{"this month", "этот месяц", "в этом месяце", "текущий месяц"}, monthOffset(0)},
+	{[]string{"next month", "следующий месяц", "в следующем месяце"}, monthOffset(1)},
+	{[]string{"last month", "прошлый месяц", "в прошлом месяце"}, monthOffset(-1)},
+}
+
+func dayOffset(days int) func(time.Time) (time.Time, time.Time) {
+	return func(today time.Time) (time.Time, time.Time) {
+		d := today.AddDate(0, 0, days)
+		return d, d
+	}
+}
+
+// weekOffset считает неделю с понедельника по воскресенье
+func weekOffset(weeks int) func(time.Time) (time.Time, time.Time) {
+	return func(today time.Time) (time.Time, time.Time) {
+		// time.Sunday == 0, поэтому сдвигаем дни так, чтобы понедельник стал нулём
+		weekday := (int(today.Weekday()) + 6) % 7
+		monday := today.AddDate(0, 0, 7*weeks-weekday)
+		return monday, monday.AddDate(0, 0, 6)
+	}
+}
+
+func monthOffset(months int) func(time.Time) (time.Time, time.Time) {
+	return func(today time.Time) (time.Time, time.Time) {
+		first := time.Date(today.Year(), today.Month()+time.Month(months), 1, 0, 0, 0, 0, today.Location())
+		return first, first.AddDate(0, 1, -1)
+	}
+}
+
+func findRelativeDate(s string) (relativeDate, bool) {
+	for _, rd := range relativeDates {
+		if slices.Contains(rd.names, s) {
+			return rd, true
+		}
+	}
+	return relativeDate{}, false
+}
+
+// parseDateRange распознаёт в строке дату или диапазон дат:
+// 02.01.2006, 20060102, 2006-01-02, диапазоны через "-" или ".."
+// и относительные обозначения на русском и английском ("сегодня",
+// "this week", "в следующем месяце"), вычисляемые от now.
+func parseDateRange(s string, now time.Time) (dateRange, bool) {
+	s = normalizeDateText(s)
+	if s == "" {
+		return dateRange{}, false
+	}
+
+	if rd, ok := findRelativeDate(s); ok {
+		today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+		from, to := rd.resolve(today)
+		return dateRange{from: from.Format(storageDateFormat), to: to.Format(storageDateFormat)}, true
+	}
+
+	if date, ok := parseAnyDate(s); ok {
+		return dateRange{from: date, to: date}, true
+	}
+
+	if from, to, ok := strings.Cut(s, ".."); ok {
+		return newDateRange(from, to)
+	}
+
+	// Дефис встречается и внутри ISO-дат, поэтому пробуем каждую позицию
+	for i := strings.Index(s, "-"); i >= 0; {
+		if r, ok := newDateRange(s[:i], s[i+1:]); ok {
+			return r, true
+		}
+		next := strings.Index(s[i+1:], "-")
+		if next < 0 {
+			break
+		}
+		i += next + 1
+	}
+
+	return dateRange{}, false
+}
+
+func newDateRange(fromStr, toStr string) (dateRange, bool) {
+	from, ok := parseAnyDate(strings.TrimSpace(fromStr))
+	if !ok {
+		return dateRange{}, false
+	}
+	to, ok := parseAnyDate(strings.TrimSpace(toStr))
+	if !ok || to < from {
+		return dateRange{}, false
+	}
+	return dateRange{from: from, to: to}, true
+}
+
+// parseAnyDate принимает одну дату в любом из поддерживаемых форматов
+func parseAnyDate(s string) (string, bool) {
+	for _, layout := range []string{searchDateFormat, storageDateFormat, isoDateFormat} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.Format(storageDateFormat), true
+		}
+	}
+	return "", false
+}
+
+// normalizeDateText приводит строку к виду ключей relativeDates:
+// нижний регистр, ё как е, "_" и повторные пробелы как один пробел,
+// тире как дефис
+func normalizeDateText(s string) string {
+	s = strings.ToLower(strings.TrimSpace(s))
+	s = strings.NewReplacer("ё", "е", "_", " ", "–", "-", "—", "-").Replace(s)
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -6,12 +6,16 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 
 	_ "modernc.org/sqlite"
 )
 
 var DB *sql.DB
 
+// stopTrashPurge останавливает фоновую очистку корзины
+var stopTrashPurge func()
+
 const (
 	defaultDBFile = "scheduler.db"
 	schema        = `
@@ -29,9 +33,13 @@ CREATE INDEX IF NOT EXISTS idx_scheduler_date ON scheduler(date);
 
 // Init инициализирует базу данных
 func Init() error {
-	dbFile := getDBFilePath()
+	cfg, err := loadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load db config: %w", err)
+	}
+	dbFile := cfg.File
 
-	_, err := os.Stat(dbFile)
+	_, err = os.Stat(dbFile)
 	install := errors.Is(err, os.ErrNotExist)
 
 	if dir := filepath.Dir(dbFile); dir != "" && dir != "." {
@@ -40,18 +48,52 @@ func Init() error {
 		}
 	}
 
-	db, err := sql.Open("sqlite", dbFile)
+	db, err := sql.Open("sqlite", cfg.DSN())
 	if err != nil {
 		return fmt.Errorf("failed to open database: %w", err)
 	}
 
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.MaxIdleConns)
+	db.SetConnMaxIdleTime(cfg.ConnIdleTime)
+
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+
 	if install {
 		if _, err := db.Exec(schema); err != nil {
+			db.Close()
 			return fmt.Errorf("failed to create schema: %w", err)
 		}
 	}
 
+	if err := migrate(db); err != nil {
+		db.Close()
+		return err
+	}
+
+	if err := prepareStatements(db); err != nil {
+		db.Close()
+		return err
+	}
+
 	DB = db
+	idempotencyTTL = cfg.IdempotencyTTL
+
+	// Очистка файлов вложений уже работает через DB, поэтому при ошибке
+	// соединение закрывается вместе с подготовленными выражениями
+	if err := initAttachments(cfg); err != nil {
+		closeStatements()
+		db.Close()
+		DB = nil
+		return err
+	}
+
+	if cfg.TrashDays > 0 {
+		stopTrashPurge = startTrashPurge(time.Duration(cfg.TrashDays) * 24 * time.Hour)
+	}
 	return nil
 }
 
@@ -63,18 +105,32 @@ func getDBFilePath() string {
 }
 
 func Close() error {
+	if stopTrashPurge != nil {
+		stopTrashPurge()
+		stopTrashPurge = nil
+	}
+	closeStatements()
+	closeCachedStmts()
 	if DB != nil {
 		return DB.Close()
 	}
 	return nil
 }
 
-func UpdateTaskDate(id int64, date string) error {
-	_, err := DB.Exec(`
-        UPDATE tasks 
-        SET date = $1 
-        WHERE id = $2`,
-		date, id,
-	)
-	return err
+// inTx выполняет fn в транзакции и фиксирует её, если fn не вернула ошибку
+func inTx(fn func(tx *sql.Tx) error) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
 }
@@ -0,0 +1,197 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrDependencyCycle возвращается, если зависимость замкнула бы цикл
+	ErrDependencyCycle = errors.New("dependency would create a cycle")
+	// ErrDependencyNotFound возвращается при удалении несуществующей зависимости
+	ErrDependencyNotFound = errors.New("dependency not found")
+	// ErrTaskBlocked возвращается при выполнении задачи с открытыми блокирующими задачами
+	ErrTaskBlocked = errors.New("task is blocked by open tasks")
+)
+
+// Задача B зависит от задачи A (A блокирует B), пока A открыта: A существует,
+// не в корзине, не выполнена и не отменена и не выполнялась после появления
+// зависимости - последнее нужно для периодических задач, которые после
+// выполнения снова получают статус todo.
+const openBlockerCond = `b.deleted_at IS NULL AND b.status IN ('todo', 'in_progress') AND NOT EXISTS (
+    SELECT 1 FROM completions c WHERE c.task_id = d.blocker_id AND c.completed_at >= d.created_at)`
+
+// blockedCond - условие "у задачи s есть открытые блокирующие задачи"
+const blockedCond = `EXISTS (SELECT 1 FROM task_dependencies d 
+    JOIN scheduler b ON b.id = d.blocker_id 
+    WHERE d.task_id = s.id AND ` + openBlockerCond + `)`
+
+// Значения TaskQuery.Blocked
+const (
+	BlockedShow = ""
+	BlockedHide = "hide"
+	BlockedOnly = "only"
+)
+
+// AddDependency отмечает, что задача taskID не может начаться,
+// пока не выполнена blockerID
+func AddDependency(ctx context.Context, taskID, blockerID int64) error {
+	if taskID == blockerID {
+		return ErrDependencyCycle
+	}
+
+	return inTx(func(tx *sql.Tx) error {
+		for _, id := range []int64{taskID, blockerID} {
+			exists, err := taskExists(tx, id)
+			if err != nil {
+				return err
+			}
+			if !exists {
+				return ErrNotFound
+			}
+		}
+
+		// Цикл появится, если blockerID уже (возможно, через другие задачи)
+		// зависит от taskID
+		var cycle bool
+		err := tx.QueryRow(`WITH RECURSIVE blockers(id) AS (
+		                        SELECT blocker_id FROM task_dependencies WHERE task_id = ? 
+		                        UNION 
+		                        SELECT d.blocker_id FROM task_dependencies d JOIN blockers ON d.task_id = blockers.id 
+		                    ) 
+		                    SELECT EXISTS(SELECT 1 FROM blockers WHERE id = ?)`, blockerID, taskID).Scan(&cycle)
+		if err != nil {
+			return fmt.Errorf("failed to check dependency cycle: %w", err)
+		}
+		if cycle {
+			return ErrDependencyCycle
+		}
+
+		res, err := tx.Exec(`INSERT INTO task_dependencies (task_id, blocker_id, created_at) VALUES (?, ?, ?) 
+		                     ON CONFLICT DO NOTHING`, taskID, blockerID, formatTimestamp(time.Now()))
+		if err != nil {
+			return fmt.Errorf("failed to add dependency: %w", err)
+		}
+
+		n, err := res.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to get rows affected: %w", err)
+		}
+		if n == 0 {
+			return nil
+		}
+		return touchDependents(ctx, tx, taskID, blockerID)
+	})
+}
+
+// DeleteDependency удаляет зависимость задачи taskID от blockerID
+func DeleteDependency(ctx context.Context, taskID, blockerID int64) error {
+	return inTx(func(tx *sql.Tx) error {
+		res, err := tx.Exec(`DELETE FROM task_dependencies WHERE task_id = ? AND blocker_id = ?`, taskID, blockerID)
+		if err != nil {
+			return fmt.Errorf("failed to delete dependency: %w", err)
+		}
+
+		n, err := res.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to get rows affected: %w", err)
+		}
+		if n == 0 {
+			return ErrDependencyNotFound
+		}
+		return touchDependents(ctx, tx, taskID, blockerID)
+	})
+}
+
+// touchDependents увеличивает версию обеих задач зависимости и пишет их в
+// журнал: у taskID меняется blocked_by, у blockerID - blocks
+func touchDependents(ctx context.Context, tx *sql.Tx, taskID, blockerID int64) error {
+	for _, id := range []int64{taskID, blockerID} {
+		before, err := snapshotTask(tx, id)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(`UPDATE scheduler SET version = version + 1 WHERE id = ?`, id)
+		if err != nil {
+			return fmt.Errorf("failed to update task version: %w", err)
+		}
+		if err := recordChange(ctx, tx, id, AuditUpdate, before); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// openBlockers возвращает ID открытых задач, блокирующих задачу id
+func openBlockers(q querier, id int64) ([]int64, error) {
+	rows, err := q.Query(`SELECT d.blocker_id FROM task_dependencies d 
+	                      JOIN scheduler b ON b.id = d.blocker_id 
+	                      WHERE d.task_id = ? AND `+openBlockerCond+` 
+	                      ORDER BY d.blocker_id`, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load blockers: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var blockerID int64
+		if err := rows.Scan(&blockerID); err != nil {
+			return nil, fmt.Errorf("row scan error: %w", err)
+		}
+		ids = append(ids, blockerID)
+	}
+	return ids, rows.Err()
+}
+
+// loadDependencies заполняет у списка задач связи с задачами вне корзины
+// и признак блокировки
+func loadDependencies(q querier, tasks []*Task) error {
+	if len(tasks) == 0 {
+		return nil
+	}
+
+	byID := make(map[int64]*Task, len(tasks))
+	placeholders := make([]string, len(tasks))
+	args := make([]interface{}, len(tasks))
+	for i, task := range tasks {
+		byID[task.ID] = task
+		placeholders[i] = "?"
+		args[i] = task.ID
+	}
+	in := "(" + strings.Join(placeholders, ", ") + ")"
+
+	// Одним запросом читаем связи в обе стороны: blocker_id задачи
+	// из списка попадает в Blocks, task_id - в BlockedBy
+	rows, err := q.Query(`SELECT d.task_id, d.blocker_id, `+openBlockerCond+` 
+	                      FROM task_dependencies d 
+	                      JOIN scheduler b ON b.id = d.blocker_id 
+	                      JOIN scheduler t ON t.id = d.task_id 
+	                      WHERE t.deleted_at IS NULL AND b.deleted_at IS NULL 
+	                        AND (d.task_id IN `+in+` OR d.blocker_id IN `+in+`) 
+	                      ORDER BY d.task_id, d.blocker_id`, append(args, args...)...)
+	if err != nil {
+		return fmt.Errorf("failed to load dependencies: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var taskID, blockerID int64
+		var open bool
+		if err := rows.Scan(&taskID, &blockerID, &open); err != nil {
+			return fmt.Errorf("row scan error: %w", err)
+		}
+		if task, ok := byID[taskID]; ok {
+			task.BlockedBy = append(task.BlockedBy, blockerID)
+			task.Blocked = task.Blocked || open
+		}
+		if blocker, ok := byID[blockerID]; ok {
+			blocker.Blocks = append(blocker.Blocks, taskID)
+		}
+	}
+	return rows.Err()
+}
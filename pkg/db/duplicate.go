@@ -0,0 +1,107 @@
+package db
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	// duplicateSimilarity - минимальное сходство заголовков похожих задач, от 0 до 1
+	duplicateSimilarity = 0.8
+	// maxDuplicates ограничивает число возвращаемых похожих задач
+	maxDuplicates = 5
+)
+
+// findDuplicates ищет открытые задачи, похожие на task: с тем же правилом
+// повторения, той же датой (у периодических дата не важна - повторение
+// сдвигает её) и заголовком, совпадающим после нормализации не менее чем
+// на duplicateSimilarity. Самые похожие задачи возвращаются первыми.
+func findDuplicates(q querier, task *Task) ([]int64, error) {
+	title := normalizeTitle(task.Title)
+	if title == "" {
+		return nil, nil
+	}
+
+	rows, err := q.Query(`SELECT id, title FROM scheduler 
+	                      WHERE deleted_at IS NULL AND status IN ('todo', 'in_progress') 
+	                        AND repeat = ? AND (repeat <> '' OR date = ?)`,
+		task.Repeat, task.Date)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search duplicates: %w", err)
+	}
+	defer rows.Close()
+
+	type candidate struct {
+		id    int64
+		score float64
+	}
+	var candidates []candidate
+	for rows.Next() {
+		var c candidate
+		var other string
+		if err := rows.Scan(&c.id, &other); err != nil {
+			return nil, fmt.Errorf("row scan error: %w", err)
+		}
+		if c.score = similarity(title, normalizeTitle(other)); c.score >= duplicateSimilarity {
+			candidates = append(candidates, c)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].score > candidates[j].score
+	})
+	if len(candidates) > maxDuplicates {
+		candidates = candidates[:maxDuplicates]
+	}
+
+	ids := make([]int64, 0, len(candidates))
+	for _, c := range candidates {
+		ids = append(ids, c.id)
+	}
+	return ids, nil
+}
+
+// normalizeTitle приводит заголовок к виду для сравнения: слова в нижнем
+// регистре через пробел, без пунктуации
+func normalizeTitle(title string) string {
+	return strings.Join(searchWords(strings.ToLower(title)), " ")
+}
+
+// similarity возвращает сходство строк от 0 до 1 по расстоянию Левенштейна
+func similarity(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	longest := len(ra)
+	if len(rb) > longest {
+		longest = len(rb)
+	}
+	if longest == 0 {
+		return 1
+	}
+	return 1 - float64(levenshtein(ra, rb))/float64(longest)
+}
+
+// levenshtein считает расстояние редактирования по двум строкам матрицы
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
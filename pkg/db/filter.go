@@ -0,0 +1,438 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// ErrInvalidQuery возвращается при синтаксической ошибке в строке поиска
+var ErrInvalidQuery = errors.New("invalid search query")
+
+// Строка поиска может содержать выражения фильтра:
+//
+//	before:01.03.2025  after:2025-03-01  on:today     - сравнение с датой задачи;
+//	on:01.03.2025-31.03.2025  on:"next week"          - принимаются диапазоны и
+//	                                                    относительные даты (см. parseDateRange)
+//	duebefore:friday  dueafter:today  due:"this week" - то же для срока выполнения
+//	due:yes  due:no                                   - есть ли у задачи срок
+//	repeat:yes  repeat:no  repeat:w                   - наличие и тип правила повторения
+//	overdue                                           - срок открытой задачи уже прошёл
+//	blocked:yes  blocked:no                           - есть ли открытые блокирующие задачи
+//	tag:работа                                        - задача отмечена меткой
+//	project:2  project:"Дом"                          - задача из проекта (ID или имя)
+//	"точная фраза"  слово                             - полнотекстовый поиск
+//	-выражение                                        - исключение
+//	a OR b, a AND b, (a OR b) c                       - комбинации, AND подразумевается
+//
+// Выражение компилируется в параметризованное SQL-условие.
+
+// filterNode - узел разобранного выражения фильтра
+type filterNode interface {
+	sql(args *[]interface{}) string
+}
+
+type andNode []filterNode
+
+func (n andNode) sql(args *[]interface{}) string {
+	return joinNodes(n, " AND ", args)
+}
+
+type orNode []filterNode
+
+func (n orNode) sql(args *[]interface{}) string {
+	return joinNodes(n, " OR ", args)
+}
+
+func joinNodes(nodes []filterNode, sep string, args *[]interface{}) string {
+	parts := make([]string, len(nodes))
+	for i, node := range nodes {
+		parts[i] = node.sql(args)
+	}
+	return "(" + strings.Join(parts, sep) + ")"
+}
+
+type notNode struct {
+	node filterNode
+}
+
+func (n notNode) sql(args *[]interface{}) string {
+	return "NOT " + n.node.sql(args)
+}
+
+// textNode ищет слово (как префикс) или точную фразу через полнотекстовый индекс
+type textNode struct {
+	words  []string
+	phrase bool
+}
+
+func (n textNode) sql(args *[]interface{}) string {
+	match := `"` + strings.Join(n.words, " ") + `"`
+	if !n.phrase {
+		match += "*"
+	}
+	*args = append(*args, match)
+	return "s.id IN (SELECT rowid FROM scheduler_fts WHERE scheduler_fts MATCH ?)"
+}
+
+// condNode - готовое SQL-условие с параметрами
+type condNode struct {
+	cond string
+	args []interface{}
+}
+
+func (n condNode) sql(args *[]interface{}) string {
+	*args = append(*args, n.args...)
+	return "(" + n.cond + ")"
+}
+
+// isPlainText сообщает, что выражение состоит только из слов, объединённых
+// через AND. Такой поиск выполняется обычным полнотекстовым запросом
+// с ранжированием и подсветкой совпадений.
+func isPlainText(node filterNode) bool {
+	switch n := node.(type) {
+	case textNode:
+		return !n.phrase
+	case andNode:
+		for _, child := range n {
+			if !isPlainText(child) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// parseFilter разбирает строку поиска в выражение фильтра. Если в строке
+// нет ни слов, ни фильтров, возвращается nil без ошибки.
+func parseFilter(search string, now time.Time) (filterNode, error) {
+	tokens, err := tokenizeFilter(search)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &filterParser{tokens: tokens, now: now}
+	node, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("%w: unexpected %q", ErrInvalidQuery, p.tokens[p.pos].text)
+	}
+	return node, nil
+}
+
+type tokenKind int
+
+const (
+	tokenWord tokenKind = iota
+	tokenPhrase
+	tokenMinus
+	tokenOpen
+	tokenClose
+	tokenOr
+	tokenAnd
+)
+
+type filterToken struct {
+	kind tokenKind
+	text string
+}
+
+func tokenizeFilter(s string) ([]filterToken, error) {
+	var tokens []filterToken
+	runes := []rune(s)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, filterToken{kind: tokenOpen, text: "("})
+			i++
+		case r == ')':
+			tokens = append(tokens, filterToken{kind: tokenClose, text: ")"})
+			i++
+		case r == '-' && (i == 0 || unicode.IsSpace(runes[i-1]) || runes[i-1] == '('):
+			// Минус означает исключение только в начале слова, а не внутри него
+			tokens = append(tokens, filterToken{kind: tokenMinus, text: "-"})
+			i++
+		case r == '"':
+			end := i + 1
+			for end < len(runes) && runes[end] != '"' {
+				end++
+			}
+			if end == len(runes) {
+				return nil, fmt.Errorf("%w: unterminated phrase", ErrInvalidQuery)
+			}
+			tokens = append(tokens, filterToken{kind: tokenPhrase, text: string(runes[i+1 : end])})
+			i = end + 1
+		default:
+			end := i
+			for end < len(runes) && !unicode.IsSpace(runes[end]) &&
+				runes[end] != '(' && runes[end] != ')' && runes[end] != '"' {
+				end++
+			}
+			// Значение фильтра может быть в кавычках: on:"this week"
+			if end < len(runes) && runes[end] == '"' && end > i && runes[end-1] == ':' {
+				closing := end + 1
+				for closing < len(runes) && runes[closing] != '"' {
+					closing++
+				}
+				if closing == len(runes) {
+					return nil, fmt.Errorf("%w: unterminated phrase", ErrInvalidQuery)
+				}
+				word := string(runes[i:end]) + string(runes[end+1:closing])
+				tokens = append(tokens, filterToken{kind: tokenWord, text: word})
+				i = closing + 1
+				continue
+			}
+
+			word := string(runes[i:end])
+			switch word {
+			case "OR":
+				tokens = append(tokens, filterToken{kind: tokenOr, text: word})
+			case "AND":
+				tokens = append(tokens, filterToken{kind: tokenAnd, text: word})
+			default:
+				tokens = append(tokens, filterToken{kind: tokenWord, text: word})
+			}
+			i = end
+		}
+	}
+
+	return tokens, nil
+}
+
+type filterParser struct {
+	tokens []filterToken
+	pos    int
+	now    time.Time
+}
+
+func (p *filterParser) peek() (filterToken, bool) {
+	if p.pos >= len(p.tokens) {
+		return filterToken{}, false
+	}
+	return p.tokens[p.pos], true
+}
+
+// parseOr: and (OR and)*
+func (p *filterParser) parseOr() (filterNode, error) {
+	var nodes orNode
+	for {
+		node, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, node)
+
+		if tok, ok := p.peek(); !ok || tok.kind != tokenOr {
+			break
+		}
+		p.pos++
+	}
+
+	if len(nodes) == 1 {
+		return nodes[0], nil
+	}
+	for _, node := range nodes {
+		if node == nil {
+			return nil, fmt.Errorf("%w: empty OR operand", ErrInvalidQuery)
+		}
+	}
+	return nodes, nil
+}
+
+// parseAnd: unary ([AND] unary)*
+func (p *filterParser) parseAnd() (filterNode, error) {
+	var nodes andNode
+	for {
+		tok, ok := p.peek()
+		if !ok || tok.kind == tokenOr || tok.kind == tokenClose {
+			break
+		}
+		if tok.kind == tokenAnd {
+			if len(nodes) == 0 {
+				return nil, fmt.Errorf("%w: unexpected AND", ErrInvalidQuery)
+			}
+			p.pos++
+			continue
+		}
+
+		node, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		if node != nil {
+			nodes = append(nodes, node)
+		}
+	}
+
+	switch len(nodes) {
+	case 0:
+		return nil, nil
+	case 1:
+		return nodes[0], nil
+	}
+	return nodes, nil
+}
+
+// parseUnary: -unary | (or) | phrase | word
+func (p *filterParser) parseUnary() (filterNode, error) {
+	tok, ok := p.peek()
+	if !ok {
+		return nil, fmt.Errorf("%w: unexpected end of query", ErrInvalidQuery)
+	}
+	p.pos++
+
+	switch tok.kind {
+	case tokenMinus:
+		node, err := p.parseUnary()
+		if err != nil || node == nil {
+			return nil, err
+		}
+		return notNode{node: node}, nil
+
+	case tokenOpen:
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if tok, ok := p.peek(); !ok || tok.kind != tokenClose {
+			return nil, fmt.Errorf("%w: missing )", ErrInvalidQuery)
+		}
+		p.pos++
+		if node == nil {
+			return nil, fmt.Errorf("%w: empty parentheses", ErrInvalidQuery)
+		}
+		return node, nil
+
+	case tokenPhrase:
+		words := searchWords(tok.text)
+		if len(words) == 0 {
+			return nil, fmt.Errorf("%w: empty phrase", ErrInvalidQuery)
+		}
+		return textNode{words: words, phrase: true}, nil
+
+	case tokenWord:
+		return p.parseTerm(tok.text)
+	}
+
+	return nil, fmt.Errorf("%w: unexpected %q", ErrInvalidQuery, tok.text)
+}
+
+// parseTerm разбирает слово: фильтр вида поле:значение, ключевое слово или текст
+func (p *filterParser) parseTerm(word string) (filterNode, error) {
+	if strings.EqualFold(word, "overdue") {
+		// Как и markOverdue, выполненные и отменённые задачи не просрочены
+		return condNode{
+			cond: "s.due <> '' AND s.due < ? AND s.status IN ('todo', 'in_progress')",
+			args: []interface{}{p.now.Format(storageDateFormat)},
+		}, nil
+	}
+
+	if field, value, ok := strings.Cut(word, ":"); ok {
+		switch strings.ToLower(field) {
+		case "before", "after", "on":
+			return p.dateFilter("s.date", field, value)
+
+		case "duebefore", "dueafter", "due":
+			switch strings.ToLower(value) {
+			case "yes":
+				return condNode{cond: "s.due <> ''"}, nil
+			case "no":
+				return condNode{cond: "s.due = ''"}, nil
+			}
+			return p.dateFilter("s.due", field, value)
+
+		case "repeat":
+			return parseRepeatFilter(value)
+
+		case "blocked":
+			switch strings.ToLower(value) {
+			case "yes":
+				return condNode{cond: blockedCond}, nil
+			case "no":
+				return condNode{cond: "NOT " + blockedCond}, nil
+			}
+			return nil, fmt.Errorf("%w: blocked: expected yes or no", ErrInvalidQuery)
+
+		case "tag":
+			tag, err := normalizeTag(value)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %v", ErrInvalidQuery, err)
+			}
+			return tagFilter(tag), nil
+
+		case "project":
+			if value == "" {
+				return nil, fmt.Errorf("%w: project: value is required", ErrInvalidQuery)
+			}
+			return projectFilter(value)
+		}
+	}
+
+	// Слова без букв и цифр (например, отдельная пунктуация) не фильтруют
+	words := searchWords(word)
+	if len(words) == 0 {
+		return nil, nil
+	}
+	if len(words) == 1 {
+		return textNode{words: words}, nil
+	}
+	nodes := make(andNode, len(words))
+	for i, w := range words {
+		nodes[i] = textNode{words: []string{w}}
+	}
+	return nodes, nil
+}
+
+// dateFilter сравнивает столбец даты column с датой или диапазоном value.
+// Фильтр field (before, after, on или они же с префиксом due) задаёт
+// сравнение. Пустой срок выполнения не попадает ни в какой диапазон.
+func (p *filterParser) dateFilter(column, field, value string) (filterNode, error) {
+	r, ok := parseDateRange(value, p.now)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s: invalid date %q", ErrInvalidQuery, field, value)
+	}
+
+	var cond string
+	var args []interface{}
+	switch strings.TrimPrefix(strings.ToLower(field), "due") {
+	case "before":
+		cond, args = column+" < ?", []interface{}{r.from}
+	case "after":
+		cond, args = column+" > ?", []interface{}{r.to}
+	default:
+		cond, args = column+" BETWEEN ? AND ?", []interface{}{r.from, r.to}
+	}
+	if column == "s.due" {
+		cond = "s.due <> '' AND " + cond
+	}
+	return condNode{cond: cond, args: args}, nil
+}
+
+func parseRepeatFilter(value string) (filterNode, error) {
+	switch strings.ToLower(value) {
+	case "yes":
+		return condNode{cond: "s.repeat <> ''"}, nil
+	case "no":
+		return condNode{cond: "s.repeat = ''"}, nil
+	case "d", "w", "m", "y":
+		rule := strings.ToLower(value)
+		return condNode{cond: "s.repeat = ? OR s.repeat LIKE ?", args: []interface{}{rule, rule + " %"}}, nil
+	}
+	return nil, fmt.Errorf("%w: repeat: expected yes, no, d, w, m or y", ErrInvalidQuery)
+}
+
+// searchWords выделяет из строки слова для полнотекстового поиска
+func searchWords(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
@@ -0,0 +1,66 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// MaxIdempotencyKeyLength - максимальная длина ключа идемпотентности
+const MaxIdempotencyKeyLength = 255
+
+// ErrIdempotencyMismatch возвращается, если ключ уже использован
+// для запроса с другим телом
+var ErrIdempotencyMismatch = errors.New("idempotency key was used with a different request")
+
+// idempotencyTTL - сколько хранится ключ идемпотентности, 0 отключает ключи
+var idempotencyTTL time.Duration
+
+// IdempotencyEnabled сообщает, хранятся ли ключи идемпотентности
+func IdempotencyEnabled() bool {
+	return idempotencyTTL > 0
+}
+
+// idempotentResult ищет ответ на запрос, выполненный ранее с ключом key.
+// Если ключа нет или он просрочен, возвращается результат с нулевым ID.
+// Если ключ пришёл с другим телом запроса, возвращается ErrIdempotencyMismatch.
+func idempotentResult(tx *sql.Tx, key, requestHash string) (AddTaskResult, error) {
+	// Просроченные ключи удаляются здесь же, отдельная очистка не нужна
+	_, err := tx.Exec(`DELETE FROM idempotency_keys WHERE created_at < ?`,
+		formatTimestamp(time.Now().Add(-idempotencyTTL)))
+	if err != nil {
+		return AddTaskResult{}, fmt.Errorf("failed to expire idempotency keys: %w", err)
+	}
+
+	var storedHash, response string
+	var res AddTaskResult
+	err = tx.QueryRow(`SELECT request_hash, task_id, status, response FROM idempotency_keys WHERE key = ?`, key).
+		Scan(&storedHash, &res.ID, &res.Status, &response)
+	if err == sql.ErrNoRows {
+		return AddTaskResult{}, nil
+	}
+	if err != nil {
+		return AddTaskResult{}, fmt.Errorf("failed to read idempotency key: %w", err)
+	}
+	if storedHash != requestHash {
+		return AddTaskResult{}, ErrIdempotencyMismatch
+	}
+	res.Replayed = true
+	if response != "" {
+		res.Response = []byte(response)
+	}
+	return res, nil
+}
+
+// storeIdempotencyKey запоминает, что запрос с ключом key добавил задачу
+// и получил ответ с кодом status и телом response
+func storeIdempotencyKey(tx *sql.Tx, key, requestHash string, id int64, status int, response []byte) error {
+	_, err := tx.Exec(`INSERT INTO idempotency_keys (key, request_hash, task_id, status, response, created_at) 
+	                   VALUES (?, ?, ?, ?, ?, ?)`,
+		key, requestHash, id, status, string(response), formatTimestamp(time.Now()))
+	if err != nil {
+		return fmt.Errorf("failed to store idempotency key: %w", err)
+	}
+	return nil
+}
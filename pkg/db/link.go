@@ -0,0 +1,91 @@
+package db
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+// taskRefPattern находит ссылки вида #42. Решётка не должна продолжать
+// слово или HTML-сущность (&#42;).
+var taskRefPattern = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_&])#(\d+)`)
+
+// TaskLink - задача, на которую ссылается комментарий, или задача,
+// которая ссылается на текущую
+type TaskLink struct {
+	ID    int64  `json:"id"`
+	Title string `json:"title"`
+}
+
+// parseTaskRefs возвращает ID задач, упомянутых в тексте, без повторов
+func parseTaskRefs(text string) []int64 {
+	var ids []int64
+	seen := make(map[int64]bool)
+	for _, match := range taskRefPattern.FindAllStringSubmatch(text, -1) {
+		id, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil || seen[id] {
+			continue
+		}
+		seen[id] = true
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// setTaskLinks заменяет ссылки задачи по её комментарию. Ссылки на
+// несуществующие задачи и на саму себя не сохраняются.
+func setTaskLinks(q querier, taskID int64, comment string) error {
+	if _, err := q.Exec(`DELETE FROM task_links WHERE task_id = ?`, taskID); err != nil {
+		return fmt.Errorf("failed to clear task links: %w", err)
+	}
+
+	for _, target := range parseTaskRefs(comment) {
+		if target == taskID {
+			continue
+		}
+		_, err := q.Exec(`INSERT INTO task_links (task_id, target_id) 
+		                  SELECT ?, id FROM scheduler WHERE id = ?`, taskID, target)
+		if err != nil {
+			return fmt.Errorf("failed to link task: %w", err)
+		}
+	}
+	return nil
+}
+
+// loadLinks заполняет у задачи ссылки из комментария и обратные ссылки.
+// Задачи из корзины не показываются, но связь с ними сохраняется
+// и появится снова после восстановления.
+func loadLinks(q querier, task *Task) error {
+	var err error
+	task.Links, err = queryLinks(q, `SELECT s.id, s.title FROM task_links l 
+	                                 JOIN scheduler s ON s.id = l.target_id 
+	                                 WHERE l.task_id = ? AND s.deleted_at IS NULL 
+	                                 ORDER BY s.id`, task.ID)
+	if err != nil {
+		return err
+	}
+
+	task.Backlinks, err = queryLinks(q, `SELECT s.id, s.title FROM task_links l 
+	                                     JOIN scheduler s ON s.id = l.task_id 
+	                                     WHERE l.target_id = ? AND s.deleted_at IS NULL 
+	                                     ORDER BY s.id`, task.ID)
+	return err
+}
+
+func queryLinks(q querier, query string, id int64) ([]TaskLink, error) {
+	rows, err := q.Query(query, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load task links: %w", err)
+	}
+	defer rows.Close()
+
+	links := make([]TaskLink, 0)
+	for rows.Next() {
+		var link TaskLink
+		if err := rows.Scan(&link.ID, &link.Title); err != nil {
+			return nil, fmt.Errorf("row scan error: %w", err)
+		}
+		links = append(links, link)
+	}
+	return links, rows.Err()
+}
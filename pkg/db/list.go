@@ -0,0 +1,493 @@
+package db
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrInvalidCursor возвращается, если курсор страницы не удалось разобрать
+	ErrInvalidCursor = errors.New("invalid cursor")
+	// ErrInvalidSort возвращается для неизвестного поля сортировки
+	ErrInvalidSort = errors.New("invalid sort, expected date, due, priority, title or created")
+)
+
+// Поля сортировки списка задач
+const (
+	SortDate     = "date"
+	SortDue      = "due"
+	SortPriority = "priority"
+	SortTitle    = "title"
+	SortCreated  = "created"
+)
+
+// TaskQuery описывает выборку списка задач
+type TaskQuery struct {
+	Search string
+	// Now - текущий момент в часовом поясе клиента для относительных дат.
+	// Нулевое значение означает часы сервера.
+	Now time.Time
+	// Tags - метки, которыми должна быть отмечена каждая задача
+	Tags []string
+	// ProjectID ограничивает список одним проектом. Без него в список
+	// не попадают задачи архивных проектов.
+	ProjectID int64
+	// Sort - поле сортировки (SortDate, SortDue, SortPriority, SortTitle, SortCreated).
+	// Пустое значение - по дате, а при полнотекстовом поиске по релевантности.
+	Sort string
+	// Desc меняет направление сортировки по основному полю
+	Desc bool
+	// Blocked - что делать с заблокированными задачами:
+	// BlockedShow, BlockedHide или BlockedOnly
+	Blocked string
+	// Statuses ограничивает список статусами задач. Пустой список - задачи
+	// в todo и in_progress.
+	Statuses []string
+	Limit    int
+	// Cursor - непрозрачный курсор из TaskPage.NextCursor предыдущей страницы
+	Cursor string
+}
+
+// TaskPage - страница списка задач
+type TaskPage struct {
+	Tasks      []*Task
+	NextCursor string
+	Total      int
+}
+
+// sortKey - выражение сортировки списка задач
+type sortKey struct {
+	expr string
+	desc bool
+}
+
+// listQuery накапливает части SQL-запроса списка задач
+type listQuery struct {
+	from    string
+	columns []string
+	where   []string
+	args    []interface{}
+	// keys задают порядок строк и однозначно определяют позицию курсора,
+	// поэтому последним ключом всегда идёт s.id
+	keys []sortKey
+	fts  bool
+}
+
+func (q *listQuery) filter(cond string, args ...interface{}) {
+	q.where = append(q.where, cond)
+	q.args = append(q.args, args...)
+}
+
+// listColumns - столбцы задачи в списке в порядке полей при чтении строки
+var listColumns = []string{
+	"s.id", "s.date", "s.due", "s.title", "s.comment", "s.repeat", "s.project_id", "s.priority",
+	"s.status", "COALESCE(s.completed_at, '')",
+}
+
+// newListQuery строит запрос по условиям поиска без учёта курсора и лимита
+func newListQuery(tq TaskQuery, now time.Time) (*listQuery, error) {
+	keys, err := sortKeys(tq.Sort, tq.Desc)
+	if err != nil {
+		return nil, err
+	}
+
+	q := &listQuery{
+		from:    "scheduler s",
+		columns: append([]string{}, listColumns...),
+		keys:    keys,
+	}
+	q.filter("s.deleted_at IS NULL")
+
+	if tq.ProjectID != 0 {
+		q.filter("s.project_id = ?", tq.ProjectID)
+	} else {
+		q.filter("s.project_id NOT IN (SELECT id FROM projects WHERE archived = 1)")
+	}
+
+	statuses := tq.Statuses
+	if len(statuses) == 0 {
+		statuses = activeStatuses
+	}
+	placeholders := make([]string, len(statuses))
+	args := make([]interface{}, len(statuses))
+	for i, status := range statuses {
+		if err := CheckStatus(status); err != nil {
+			return nil, err
+		}
+		placeholders[i] = "?"
+		args[i] = status
+	}
+	q.filter("s.status IN ("+strings.Join(placeholders, ", ")+")", args...)
+
+	switch tq.Blocked {
+	case BlockedShow:
+	case BlockedHide:
+		q.filter("NOT " + blockedCond)
+	case BlockedOnly:
+		q.filter(blockedCond)
+	default:
+		return nil, fmt.Errorf("%w: blocked must be hide or only", ErrInvalidQuery)
+	}
+
+	for _, name := range tq.Tags {
+		tag, err := normalizeTag(name)
+		if err != nil {
+			return nil, err
+		}
+		var args []interface{}
+		cond := tagFilter(tag).sql(&args)
+		q.filter(cond, args...)
+	}
+
+	search := tq.Search
+	if search == "" {
+		return q, nil
+	}
+
+	// Дата, диапазон или относительная дата вроде "завтра"
+	if r, ok := parseDateRange(search, now); ok {
+		q.filter("s.date BETWEEN ? AND ?", r.from, r.to)
+		return q, nil
+	}
+
+	node, err := parseFilter(search, now)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case node == nil:
+		// В строке нет слов для полнотекстового поиска - ищем подстроку
+		searchTerm := "%" + strings.ToLower(search) + "%"
+		q.filter("(LOWER(s.title) LIKE ? OR LOWER(s.comment) LIKE ?)", searchTerm, searchTerm)
+	case isPlainText(node):
+		q.searchText(search, tq.Sort == "")
+	default:
+		var args []interface{}
+		cond := node.sql(&args)
+		q.filter(cond, args...)
+	}
+
+	return q, nil
+}
+
+// sortKeys возвращает ключи сортировки для поля sort. Внутри одной даты
+// задачи идут в ручном порядке (см. rank.go), а не упорядоченные вручную -
+// после них, срочные первыми. Последним ключом всегда идёт s.id.
+func sortKeys(sort string, desc bool) ([]sortKey, error) {
+	switch sort {
+	case "", SortDate:
+		return []sortKey{
+			{expr: "s.date", desc: desc},
+			{expr: "(s.rank = '')"},
+			{expr: "s.rank"},
+			{expr: "s.priority"},
+			{expr: "s.id"},
+		}, nil
+	case SortDue:
+		// Задачи без срока идут после задач со сроком при любом направлении
+		return []sortKey{
+			{expr: "(s.due = '')"},
+			{expr: "s.due", desc: desc},
+			{expr: "s.date"},
+			{expr: "s.priority"},
+			{expr: "s.id"},
+		}, nil
+	case SortPriority:
+		return []sortKey{{expr: "s.priority", desc: desc}, {expr: "s.date"}, {expr: "s.id"}}, nil
+	case SortTitle:
+		return []sortKey{{expr: "s.title", desc: desc}, {expr: "s.date"}, {expr: "s.priority"}, {expr: "s.id"}}, nil
+	case SortCreated:
+		// ID выдаются по возрастанию, поэтому порядок ID - порядок создания
+		return []sortKey{{expr: "s.id", desc: desc}}, nil
+	}
+	return nil, ErrInvalidSort
+}
+
+// searchText добавляет полнотекстовый поиск с подсветкой. Если relevance,
+// сначала идут самые релевантные задачи.
+func (q *listQuery) searchText(search string, relevance bool) {
+	q.from = "scheduler_fts JOIN scheduler s ON s.id = scheduler_fts.rowid"
+	q.columns = append(q.columns,
+		"highlight(scheduler_fts, 0, '"+matchOpen+"', '"+matchClose+"')",
+		"snippet(scheduler_fts, 1, '"+matchOpen+"', '"+matchClose+"', '…', 16)")
+	q.filter("scheduler_fts MATCH ?", ftsQuery(search))
+	if relevance {
+		// Внутри одинаковой релевантности - по дате
+		q.keys = append([]sortKey{{expr: "bm25(scheduler_fts)"}}, q.keys...)
+	}
+	q.fts = true
+}
+
+func (q *listQuery) whereSQL(extra ...string) string {
+	conds := append(append([]string{}, q.where...), extra...)
+	if len(conds) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(conds, " AND ")
+}
+
+// seek возвращает условие "строка идёт после курсора" для ключей сортировки:
+// (k1 > v1) OR (k1 = v1 AND k2 > v2) OR ...
+func (q *listQuery) seek(values []interface{}) (string, []interface{}) {
+	var ors []string
+	var args []interface{}
+
+	for i, key := range q.keys {
+		var ands []string
+		for j := 0; j < i; j++ {
+			ands = append(ands, q.keys[j].expr+" = ?")
+			args = append(args, values[j])
+		}
+		op := ">"
+		if key.desc {
+			op = "<"
+		}
+		ands = append(ands, key.expr+" "+op+" ?")
+		args = append(args, values[i])
+		ors = append(ors, "("+strings.Join(ands, " AND ")+")")
+	}
+
+	return "(" + strings.Join(ors, " OR ") + ")", args
+}
+
+func (q *listQuery) orderSQL() string {
+	parts := make([]string, len(q.keys))
+	for i, key := range q.keys {
+		parts[i] = key.expr + " ASC"
+		if key.desc {
+			parts[i] = key.expr + " DESC"
+		}
+	}
+	return " ORDER BY " + strings.Join(parts, ", ")
+}
+
+// ids возвращает ID первых limit задач выборки в порядке сортировки
+func (q *listQuery) ids(qr querier, limit int) ([]int64, error) {
+	rows, err := qr.Query("SELECT s.id FROM "+q.from+q.whereSQL()+q.orderSQL()+" LIMIT ?",
+		append(append([]interface{}{}, q.args...), limit)...)
+	if err != nil {
+		return nil, fmt.Errorf("database query error: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("row scan error: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// Tasks возвращает страницу списка задач с поддержкой поиска
+func Tasks(tq TaskQuery) (*TaskPage, error) {
+	now := tq.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	q, err := newListQuery(tq, now)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &TaskPage{Tasks: make([]*Task, 0)}
+
+	countSQL := "SELECT COUNT(*) FROM " + q.from + q.whereSQL()
+	if err := preparedQueryRow(countSQL, q.args...).Scan(&page.Total); err != nil {
+		return nil, fmt.Errorf("database query error: %w", err)
+	}
+
+	args := append([]interface{}{}, q.args...)
+	var extra []string
+	if tq.Cursor != "" {
+		values, err := decodeCursor(tq.Cursor, len(q.keys))
+		if err != nil {
+			return nil, err
+		}
+		cond, seekArgs := q.seek(values)
+		extra = append(extra, cond)
+		args = append(args, seekArgs...)
+	}
+
+	keyColumns := make([]string, len(q.keys))
+	for i, key := range q.keys {
+		keyColumns[i] = key.expr
+	}
+
+	// Читаем на одну строку больше, чтобы понять, есть ли следующая страница
+	listSQL := "SELECT " + strings.Join(q.columns, ", ") + ", " + strings.Join(keyColumns, ", ") +
+		" FROM " + q.from + q.whereSQL(extra...) + q.orderSQL() + " LIMIT ?"
+	args = append(args, tq.Limit+1)
+
+	rows, err := preparedQuery(listSQL, args...)
+	if err != nil {
+		return nil, fmt.Errorf("database query error: %w", err)
+	}
+	defer rows.Close()
+
+	var lastKeys []interface{}
+	for rows.Next() {
+		if len(page.Tasks) == tq.Limit {
+			cursor, err := encodeCursor(lastKeys)
+			if err != nil {
+				return nil, err
+			}
+			page.NextCursor = cursor
+			break
+		}
+
+		var task Task
+		dest := []interface{}{&task.ID, &task.Date, &task.Due, &task.Title, &task.Comment, &task.Repeat,
+			&task.ProjectID, &task.Priority, &task.Status, &task.CompletedAt}
+		if q.fts {
+			task.Highlight = &Highlight{}
+			dest = append(dest, &task.Highlight.Title, &task.Highlight.Comment)
+		}
+		keys := make([]interface{}, len(q.keys))
+		for i := range keys {
+			dest = append(dest, &keys[i])
+		}
+
+		if err := rows.Scan(dest...); err != nil {
+			return nil, fmt.Errorf("row scan error: %w", err)
+		}
+		if task.Highlight != nil {
+			task.Highlight.Title = highlightHTML(task.Highlight.Title)
+			task.Highlight.Comment = highlightHTML(task.Highlight.Comment)
+		}
+		page.Tasks = append(page.Tasks, &task)
+		lastKeys = keys
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	rows.Close()
+	markOverdue(page.Tasks, now)
+
+	if err := loadTags(DB, page.Tasks); err != nil {
+		return nil, err
+	}
+	if err := loadChecklistProgress(DB, page.Tasks); err != nil {
+		return nil, err
+	}
+	if err := loadDependencies(DB, page.Tasks); err != nil {
+		return nil, err
+	}
+
+	return page, nil
+}
+
+// encodeCursor упаковывает значения ключей сортировки последней строки страницы
+func encodeCursor(values []interface{}) (string, error) {
+	data, err := json.Marshal(values)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode cursor: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// decodeCursor распаковывает курсор, проверяя число ключей сортировки
+func decodeCursor(cursor string, keys int) ([]interface{}, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var raw []interface{}
+	if err := dec.Decode(&raw); err != nil || len(raw) != keys {
+		return nil, ErrInvalidCursor
+	}
+
+	values := make([]interface{}, len(raw))
+	for i, v := range raw {
+		switch v := v.(type) {
+		case json.Number:
+			// Целые сравниваются как целые, иначе SQLite сравнит их как REAL
+			if n, err := v.Int64(); err == nil {
+				values[i] = n
+			} else if f, err := v.Float64(); err == nil {
+				values[i] = f
+			} else {
+				return nil, ErrInvalidCursor
+			}
+		case string:
+			values[i] = v
+		default:
+			return nil, ErrInvalidCursor
+		}
+	}
+	return values, nil
+}
+
+// maxCachedStmts ограничивает число подготовленных динамических запросов
+const maxCachedStmts = 128
+
+// stmtCache хранит подготовленные выражения для запросов, собираемых
+// динамически: каждая форма запроса готовится один раз
+var stmtCache = struct {
+	sync.Mutex
+	stmts map[string]*sql.Stmt
+}{stmts: make(map[string]*sql.Stmt)}
+
+// cachedStmt возвращает подготовленное выражение для запроса или nil,
+// если кэш заполнен или запрос не удалось подготовить
+func cachedStmt(query string) *sql.Stmt {
+	stmtCache.Lock()
+	defer stmtCache.Unlock()
+
+	if stmt, ok := stmtCache.stmts[query]; ok {
+		return stmt
+	}
+	if len(stmtCache.stmts) >= maxCachedStmts {
+		return nil
+	}
+
+	stmt, err := DB.Prepare(query)
+	if err != nil {
+		return nil
+	}
+	stmtCache.stmts[query] = stmt
+	return stmt
+}
+
+// preparedQuery выполняет запрос через кэш подготовленных выражений
+func preparedQuery(query string, args ...interface{}) (*sql.Rows, error) {
+	if stmt := cachedStmt(query); stmt != nil {
+		return stmt.Query(args...)
+	}
+	return DB.Query(query, args...)
+}
+
+// preparedQueryRow выполняет запрос одной строки через кэш подготовленных выражений
+func preparedQueryRow(query string, args ...interface{}) *sql.Row {
+	if stmt := cachedStmt(query); stmt != nil {
+		return stmt.QueryRow(args...)
+	}
+	return DB.QueryRow(query, args...)
+}
+
+// closeCachedStmts освобождает подготовленные динамические запросы
+func closeCachedStmts() {
+	stmtCache.Lock()
+	defer stmtCache.Unlock()
+
+	for key, stmt := range stmtCache.stmts {
+		stmt.Close()
+		delete(stmtCache.stmts, key)
+	}
+}
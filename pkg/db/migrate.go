@@ -0,0 +1,203 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// migrations содержит изменения схемы в порядке применения.
+// Номер последней применённой миграции хранится в PRAGMA user_version,
+// поэтому новые миграции добавляются только в конец списка.
+var migrations = []string{
+	// 1: версия задачи для оптимистичной блокировки
+	`ALTER TABLE scheduler ADD COLUMN version INTEGER NOT NULL DEFAULT 1;`,
+	// 2: история выполнения задач
+	`CREATE TABLE completions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    task_id INTEGER NOT NULL,
+    title VARCHAR(255) NOT NULL DEFAULT '',
+    scheduled_date CHAR(8) NOT NULL DEFAULT '',
+    completed_at VARCHAR(32) NOT NULL,
+    note TEXT NOT NULL DEFAULT ''
+);
+CREATE INDEX idx_completions_task ON completions(task_id, completed_at);
+CREATE INDEX idx_completions_completed_at ON completions(completed_at);`,
+	// 3: корзина - мягкое удаление задач
+	`ALTER TABLE scheduler ADD COLUMN deleted_at VARCHAR(32);
+CREATE INDEX idx_scheduler_deleted_at ON scheduler(deleted_at);`,
+	// 4: журнал изменений задач
+	`CREATE TABLE audit_log (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    task_id INTEGER NOT NULL,
+    action VARCHAR(16) NOT NULL,
+    actor VARCHAR(255) NOT NULL DEFAULT '',
+    before_json TEXT,
+    after_json TEXT,
+    created_at VARCHAR(32) NOT NULL
+);
+CREATE INDEX idx_audit_log_task ON audit_log(task_id, id);
+CREATE INDEX idx_audit_log_created_at ON audit_log(created_at);`,
+	// 5: полнотекстовый поиск по заголовку и комментарию
+	`CREATE VIRTUAL TABLE scheduler_fts USING fts5(
+    title, comment,
+    content = 'scheduler', content_rowid = 'id',
+    tokenize = 'unicode61'
+);
+CREATE TRIGGER scheduler_fts_ai AFTER INSERT ON scheduler BEGIN
+    INSERT INTO scheduler_fts (rowid, title, comment) VALUES (new.id, new.title, new.comment);
+END;
+CREATE TRIGGER scheduler_fts_ad AFTER DELETE ON scheduler BEGIN
+    INSERT INTO scheduler_fts (scheduler_fts, rowid, title, comment)
+    VALUES ('delete', old.id, old.title, old.comment);
+END;
+CREATE TRIGGER scheduler_fts_au AFTER UPDATE OF title, comment ON scheduler BEGIN
+    INSERT INTO scheduler_fts (scheduler_fts, rowid, title, comment)
+    VALUES ('delete', old.id, old.title, old.comment);
+    INSERT INTO scheduler_fts (rowid, title, comment) VALUES (new.id, new.title, new.comment);
+END;
+INSERT INTO scheduler_fts (scheduler_fts) VALUES ('rebuild');`,
+	// 6: метки задач
+	`CREATE TABLE tags (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name VARCHAR(64) NOT NULL UNIQUE
+);
+CREATE TABLE task_tags (
+    task_id INTEGER NOT NULL REFERENCES scheduler(id) ON DELETE CASCADE,
+    tag_id INTEGER NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+    PRIMARY KEY (task_id, tag_id)
+);
+CREATE INDEX idx_task_tags_tag ON task_tags(tag_id, task_id);`,
+	// 7: проекты. Существующие задачи попадают во входящие (id = 1).
+	// REFERENCES не указан: SQLite не позволяет добавить столбец-ссылку
+	// с ненулевым значением по умолчанию при включённых внешних ключах.
+	`CREATE TABLE projects (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name VARCHAR(255) NOT NULL UNIQUE,
+    color VARCHAR(7) NOT NULL DEFAULT '',
+    archived INTEGER NOT NULL DEFAULT 0
+);
+INSERT INTO projects (id, name) VALUES (1, 'Inbox');
+ALTER TABLE scheduler ADD COLUMN project_id INTEGER NOT NULL DEFAULT 1;
+CREATE INDEX idx_scheduler_project ON scheduler(project_id, date);`,
+	// 8: приоритет задачи, 1 - самый срочный
+	`ALTER TABLE scheduler ADD COLUMN priority INTEGER NOT NULL DEFAULT 4;
+CREATE INDEX idx_scheduler_date_priority ON scheduler(date, priority);`,
+	// 9: ручной порядок задач внутри даты, см. rank.go
+	`ALTER TABLE scheduler ADD COLUMN rank VARCHAR(255) NOT NULL DEFAULT '';
+CREATE INDEX idx_scheduler_date_rank ON scheduler(date, rank);`,
+	// 10: чек-листы задач
+	`CREATE TABLE checklist_items (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    task_id INTEGER NOT NULL REFERENCES scheduler(id) ON DELETE CASCADE,
+    title VARCHAR(255) NOT NULL,
+    done INTEGER NOT NULL DEFAULT 0,
+    position INTEGER NOT NULL DEFAULT 0
+);
+CREATE INDEX idx_checklist_items_task ON checklist_items(task_id, position);`,
+	// 11: зависимости между задачами, см. dependency.go
+	`CREATE TABLE task_dependencies (
+    task_id INTEGER NOT NULL REFERENCES scheduler(id) ON DELETE CASCADE,
+    blocker_id INTEGER NOT NULL REFERENCES scheduler(id) ON DELETE CASCADE,
+    created_at VARCHAR(32) NOT NULL,
+    PRIMARY KEY (task_id, blocker_id)
+);
+CREATE INDEX idx_task_dependencies_blocker ON task_dependencies(blocker_id);`,
+	// 12: ссылки #id из комментариев задач, см. link.go
+	`CREATE TABLE task_links (
+    task_id INTEGER NOT NULL REFERENCES scheduler(id) ON DELETE CASCADE,
+    target_id INTEGER NOT NULL REFERENCES scheduler(id) ON DELETE CASCADE,
+    PRIMARY KEY (task_id, target_id)
+);
+CREATE INDEX idx_task_links_target ON task_links(target_id);`,
+	// 13: вложения задач. Файлы удалённых вложений, включая каскадное
+	// удаление с задачей, попадают в очередь на удаление с диска.
+	`CREATE TABLE attachments (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    task_id INTEGER NOT NULL REFERENCES scheduler(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    mime_type VARCHAR(255) NOT NULL,
+    size INTEGER NOT NULL,
+    stored_as VARCHAR(64) NOT NULL UNIQUE,
+    created_at VARCHAR(32) NOT NULL
+);
+CREATE INDEX idx_attachments_task ON attachments(task_id);
+CREATE TABLE attachment_files_deleted (
+    stored_as VARCHAR(64) PRIMARY KEY
+);
+CREATE TRIGGER attachments_ad AFTER DELETE ON attachments BEGIN
+    INSERT OR IGNORE INTO attachment_files_deleted (stored_as) VALUES (old.stored_as);
+END;`,
+	// 14: заметки к задачам
+	`CREATE TABLE task_notes (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    task_id INTEGER NOT NULL REFERENCES scheduler(id) ON DELETE CASCADE,
+    author VARCHAR(255) NOT NULL DEFAULT '',
+    text TEXT NOT NULL,
+    created_at VARCHAR(32) NOT NULL,
+    edited_at VARCHAR(32)
+);
+CREATE INDEX idx_task_notes_task ON task_notes(task_id, id);`,
+	// 15: статус задачи и момент выполнения одноразовой задачи
+	`ALTER TABLE scheduler ADD COLUMN status VARCHAR(16) NOT NULL DEFAULT 'todo';
+ALTER TABLE scheduler ADD COLUMN completed_at VARCHAR(32);
+CREATE INDEX idx_scheduler_status ON scheduler(status, date);`,
+	// 16: срок выполнения отдельно от запланированной даты, '' - без срока
+	`ALTER TABLE scheduler ADD COLUMN due CHAR(8) NOT NULL DEFAULT '';
+CREATE INDEX idx_scheduler_due ON scheduler(due);`,
+	// 17: шаблоны задач, см. template.go. Метки хранятся списком JSON.
+	`CREATE TABLE templates (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name VARCHAR(255) NOT NULL UNIQUE,
+    title VARCHAR(255) NOT NULL,
+    comment TEXT NOT NULL DEFAULT '',
+    repeat VARCHAR(128) NOT NULL DEFAULT '',
+    tags TEXT NOT NULL DEFAULT '[]',
+    project_id INTEGER NOT NULL DEFAULT 1,
+    priority INTEGER NOT NULL DEFAULT 4,
+    date_offset INTEGER NOT NULL DEFAULT 0,
+    due_offset INTEGER
+);`,
+	// 18: ключи идемпотентности добавления задач, см. idempotency.go
+	`CREATE TABLE idempotency_keys (
+    key VARCHAR(255) PRIMARY KEY,
+    request_hash CHAR(64) NOT NULL,
+    task_id INTEGER NOT NULL,
+    created_at VARCHAR(32) NOT NULL
+);
+CREATE INDEX idx_idempotency_keys_created_at ON idempotency_keys(created_at);`,
+	// 19: сохранённый ответ на запрос с ключом идемпотентности
+	`ALTER TABLE idempotency_keys ADD COLUMN status INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE idempotency_keys ADD COLUMN response TEXT NOT NULL DEFAULT '';`,
+}
+
+// migrate применяет к базе ещё не выполненные миграции
+func migrate(db *sql.DB) error {
+	var current int
+	if err := db.QueryRow(`PRAGMA user_version`).Scan(&current); err != nil {
+		return fmt.Errorf("failed to read schema version: %w", err)
+	}
+
+	for i := current; i < len(migrations); i++ {
+		tx, err := db.Begin()
+		if err != nil {
+			return fmt.Errorf("failed to begin migration %d: %w", i+1, err)
+		}
+
+		if _, err := tx.Exec(migrations[i]); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to apply migration %d: %w", i+1, err)
+		}
+
+		// PRAGMA не поддерживает параметры, номер подставляется напрямую
+		if _, err := tx.Exec(fmt.Sprintf(`PRAGMA user_version = %d`, i+1)); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to update schema version: %w", err)
+		}
+
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit migration %d: %w", i+1, err)
+		}
+	}
+
+	return nil
+}
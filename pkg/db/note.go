@@ -0,0 +1,137 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const maxNoteLength = 10000
+
+var (
+	// ErrInvalidNote возвращается для пустой или слишком длинной заметки
+	ErrInvalidNote = errors.New("invalid note: text must be 1-10000 characters")
+	// ErrNoteNotFound возвращается, если заметки с указанным ID нет
+	ErrNoteNotFound = errors.New("note not found")
+)
+
+// Note - заметка к задаче. В отличие от комментария задачи заметки
+// не перезаписываются при обновлении задачи, а добавляются по одной.
+type Note struct {
+	ID        int64  `json:"id"`
+	TaskID    int64  `json:"task_id"`
+	Author    string `json:"author"`
+	Text      string `json:"text"`
+	CreatedAt string `json:"created_at"`
+	// EditedAt заполняется, если заметку меняли после создания
+	EditedAt string `json:"edited_at,omitempty"`
+}
+
+func normalizeNoteText(text string) (string, error) {
+	text = strings.TrimSpace(text)
+	if text == "" || utf8.RuneCountInString(text) > maxNoteLength {
+		return "", ErrInvalidNote
+	}
+	return text, nil
+}
+
+// Notes возвращает заметки задачи, начиная с самых ранних
+func Notes(taskID int64) ([]*Note, error) {
+	exists, err := taskExists(DB, taskID)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrNotFound
+	}
+
+	rows, err := DB.Query(`SELECT id, task_id, author, text, created_at, edited_at 
+	                       FROM task_notes WHERE task_id = ? ORDER BY id`, taskID)
+	if err != nil {
+		return nil, fmt.Errorf("database query error: %w", err)
+	}
+	defer rows.Close()
+
+	notes := make([]*Note, 0)
+	for rows.Next() {
+		var n Note
+		var editedAt sql.NullString
+		if err := rows.Scan(&n.ID, &n.TaskID, &n.Author, &n.Text, &n.CreatedAt, &editedAt); err != nil {
+			return nil, fmt.Errorf("row scan error: %w", err)
+		}
+		n.EditedAt = editedAt.String
+		notes = append(notes, &n)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return notes, nil
+}
+
+// AddNote добавляет заметку к задаче от имени автора из контекста
+func AddNote(ctx context.Context, note *Note) (int64, error) {
+	text, err := normalizeNoteText(note.Text)
+	if err != nil {
+		return 0, err
+	}
+	note.Text = text
+	note.Author = actorFrom(ctx)
+	note.CreatedAt = formatTimestamp(time.Now())
+
+	res, err := DB.Exec(`INSERT INTO task_notes (task_id, author, text, created_at) 
+	                     SELECT id, ?, ?, ? FROM scheduler WHERE id = ? AND deleted_at IS NULL`,
+		note.Author, note.Text, note.CreatedAt, note.TaskID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to insert note: %w", err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if n == 0 {
+		return 0, ErrNotFound
+	}
+
+	return res.LastInsertId()
+}
+
+// UpdateNote меняет текст заметки и отмечает время правки
+func UpdateNote(id int64, text string) error {
+	text, err := normalizeNoteText(text)
+	if err != nil {
+		return err
+	}
+
+	res, err := DB.Exec(`UPDATE task_notes SET text = ?, edited_at = ? WHERE id = ?`,
+		text, formatTimestamp(time.Now()), id)
+	if err != nil {
+		return fmt.Errorf("failed to update note: %w", err)
+	}
+	return checkNoteFound(res)
+}
+
+// DeleteNote удаляет заметку
+func DeleteNote(id int64) error {
+	res, err := DB.Exec(`DELETE FROM task_notes WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete note: %w", err)
+	}
+	return checkNoteFound(res)
+}
+
+func checkNoteFound(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if n == 0 {
+		return ErrNoteNotFound
+	}
+	return nil
+}
@@ -0,0 +1,267 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// InboxProjectID - проект по умолчанию. В него попадают задачи без проекта
+// и задачи удалённых проектов, поэтому его нельзя удалить или архивировать.
+const InboxProjectID = 1
+
+const maxProjectNameLength = 255
+
+var (
+	// ErrInvalidProject возвращается для пустого имени или неверного цвета проекта
+	ErrInvalidProject = errors.New("invalid project")
+	// ErrProjectExists возвращается при попытке занять имя другого проекта
+	ErrProjectExists = errors.New("project already exists")
+	// ErrProjectNotFound возвращается, если проекта с указанным ID нет
+	ErrProjectNotFound = errors.New("project not found")
+	// ErrProjectArchived возвращается при попытке добавить задачу в архивный проект
+	ErrProjectArchived = errors.New("project is archived")
+	// ErrInboxProject возвращается при попытке удалить или архивировать входящие
+	ErrInboxProject = errors.New("inbox project cannot be deleted or archived")
+)
+
+// projectColor - цвет проекта в формате #rrggbb
+var projectColor = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+// Project - список задач со своим именем и цветом
+type Project struct {
+	ID       int64  `json:"id"`
+	Name     string `json:"name"`
+	Color    string `json:"color"`
+	Archived bool   `json:"archived"`
+	// Count - число задач проекта вне корзины
+	Count int `json:"count"`
+}
+
+// normalizeProject проверяет имя и цвет проекта
+func normalizeProject(p *Project) error {
+	p.Name = strings.TrimSpace(p.Name)
+	if p.Name == "" || utf8.RuneCountInString(p.Name) > maxProjectNameLength {
+		return fmt.Errorf("%w: name must be 1-%d characters", ErrInvalidProject, maxProjectNameLength)
+	}
+	p.Color = strings.ToLower(strings.TrimSpace(p.Color))
+	if p.Color != "" && !projectColor.MatchString(p.Color) {
+		return fmt.Errorf("%w: color must be in #rrggbb format", ErrInvalidProject)
+	}
+	return nil
+}
+
+// Projects возвращает проекты с числом задач. Архивные проекты
+// включаются в список, только если withArchived.
+func Projects(withArchived bool) ([]*Project, error) {
+	query := `SELECT p.id, p.name, p.color, p.archived, COUNT(s.id) 
+	          FROM projects p 
+	          LEFT JOIN scheduler s ON s.project_id = p.id AND s.deleted_at IS NULL`
+	if !withArchived {
+		query += ` WHERE p.archived = 0`
+	}
+	query += ` GROUP BY p.id ORDER BY p.id`
+
+	rows, err := DB.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("database query error: %w", err)
+	}
+	defer rows.Close()
+
+	projects := make([]*Project, 0)
+	for rows.Next() {
+		var p Project
+		if err := rows.Scan(&p.ID, &p.Name, &p.Color, &p.Archived, &p.Count); err != nil {
+			return nil, fmt.Errorf("row scan error: %w", err)
+		}
+		projects = append(projects, &p)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return projects, nil
+}
+
+// AddProject создаёт проект
+func AddProject(p *Project) (int64, error) {
+	if err := normalizeProject(p); err != nil {
+		return 0, err
+	}
+
+	res, err := DB.Exec(`INSERT INTO projects (name, color, archived) VALUES (?, ?, ?) 
+	                     ON CONFLICT(name) DO NOTHING`, p.Name, p.Color, p.Archived)
+	if err != nil {
+		return 0, fmt.Errorf("failed to insert project: %w", err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return 0, ErrProjectExists
+	}
+
+	return res.LastInsertId()
+}
+
+// UpdateProject меняет имя, цвет и признак архива проекта
+func UpdateProject(p *Project) error {
+	if err := normalizeProject(p); err != nil {
+		return err
+	}
+	if p.ID == InboxProjectID && p.Archived {
+		return ErrInboxProject
+	}
+
+	var exists bool
+	err := DB.QueryRow(`SELECT EXISTS(SELECT 1 FROM projects WHERE name = ? AND id <> ?)`, p.Name, p.ID).
+		Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to check project: %w", err)
+	}
+	if exists {
+		return ErrProjectExists
+	}
+
+	res, err := DB.Exec(`UPDATE projects SET name = ?, color = ?, archived = ? WHERE id = ?`,
+		p.Name, p.Color, p.Archived, p.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update project: %w", err)
+	}
+	return checkProjectFound(res)
+}
+
+// DeleteProject удаляет проект, перенося его задачи, включая задачи
+// из корзины, и шаблоны во входящие. Каждый перенос задачи попадает
+// в журнал изменений.
+func DeleteProject(ctx context.Context, id int64) error {
+	if id == InboxProjectID {
+		return ErrInboxProject
+	}
+
+	return inTx(func(tx *sql.Tx) error {
+		res, err := tx.Exec(`DELETE FROM projects WHERE id = ?`, id)
+		if err != nil {
+			return fmt.Errorf("failed to delete project: %w", err)
+		}
+		if err := checkProjectFound(res); err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(`UPDATE templates SET project_id = ? WHERE project_id = ?`, InboxProjectID, id)
+		if err != nil {
+			return fmt.Errorf("failed to move project templates: %w", err)
+		}
+
+		ids, err := projectTaskIDs(tx, id)
+		if err != nil {
+			return err
+		}
+
+		for _, taskID := range ids {
+			before, err := snapshotTask(tx, taskID)
+			if err != nil {
+				return err
+			}
+			_, err = tx.Exec(`UPDATE scheduler SET project_id = ?, version = version + 1 WHERE id = ?`,
+				InboxProjectID, taskID)
+			if err != nil {
+				return fmt.Errorf("failed to move project task: %w", err)
+			}
+			if err := recordChange(ctx, tx, taskID, AuditMove, before); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func projectTaskIDs(q querier, projectID int64) ([]int64, error) {
+	rows, err := q.Query(`SELECT id FROM scheduler WHERE project_id = ?`, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select project tasks: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("row scan error: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+func checkProjectFound(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if n == 0 {
+		return ErrProjectNotFound
+	}
+	return nil
+}
+
+// checkTaskProject проверяет, что в проект можно поместить задачу.
+// Нулевой ID означает входящие.
+func checkTaskProject(q querier, id int64) (int64, error) {
+	if id == 0 {
+		return InboxProjectID, nil
+	}
+
+	var archived bool
+	err := q.QueryRow(`SELECT archived FROM projects WHERE id = ?`, id).Scan(&archived)
+	if err == sql.ErrNoRows {
+		return 0, ErrProjectNotFound
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to check project: %w", err)
+	}
+	if archived {
+		return 0, ErrProjectArchived
+	}
+	return id, nil
+}
+
+// projectFilter - условие "задача из проекта": по ID или по имени.
+// Имя сравнивается без учёта регистра в Go: LOWER в SQLite
+// понимает только латиницу.
+func projectFilter(value string) (condNode, error) {
+	if id, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return condNode{cond: "s.project_id = ?", args: []interface{}{id}}, nil
+	}
+
+	rows, err := DB.Query(`SELECT id, name FROM projects`)
+	if err != nil {
+		return condNode{}, fmt.Errorf("database query error: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []string
+	var args []interface{}
+	for rows.Next() {
+		var id int64
+		var name string
+		if err := rows.Scan(&id, &name); err != nil {
+			return condNode{}, fmt.Errorf("row scan error: %w", err)
+		}
+		if strings.EqualFold(name, value) {
+			ids = append(ids, "?")
+			args = append(args, id)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return condNode{}, fmt.Errorf("rows error: %w", err)
+	}
+
+	if len(ids) == 0 {
+		// Проекта с таким именем нет - условию не соответствует ни одна задача
+		return condNode{cond: "0"}, nil
+	}
+	return condNode{cond: "s.project_id IN (" + strings.Join(ids, ", ") + ")", args: args}, nil
+}
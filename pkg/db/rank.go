@@ -0,0 +1,254 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Ранг задаёт ручной порядок задач внутри одной даты. Это строка из цифр
+// rankDigits, которая сравнивается побайтово как дробная часть числа:
+// между любыми двумя рангами всегда есть третий, поэтому перестановка
+// задачи меняет только её собственную строку. Пустой ранг означает, что
+// задачу ещё не упорядочивали вручную - такие задачи идут после
+// упорядоченных, по приоритету.
+
+// rankDigits - цифры ранга в порядке возрастания кодов ASCII
+const rankDigits = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// maxRankLength - длина ранга, после которой ранги дня назначаются заново.
+// Вставка раз за разом между одними и теми же соседями удлиняет ранг
+// примерно на цифру за шесть перестановок.
+const maxRankLength = 32
+
+var (
+	// ErrInvalidReorder возвращается, если соседняя задача не указана,
+	// совпадает с переставляемой или относится к другой дате
+	ErrInvalidReorder = errors.New("invalid reorder: neighbour must be another task with the same date")
+)
+
+// rankBetween возвращает ранг строго между a и b. Пустой a означает начало
+// списка, пустой b - конец. Ранги не должны оканчиваться на цифру "0".
+func rankBetween(a, b string) string {
+	if b != "" {
+		// Общий префикс переносится в результат без изменений
+		n := 0
+		for n < len(b) && rankDigitAt(a, n) == b[n] {
+			n++
+		}
+		if n > 0 {
+			return b[:n] + rankBetween(a[min(n, len(a)):], b[n:])
+		}
+	}
+
+	digitA := 0
+	if a != "" {
+		digitA = strings.IndexByte(rankDigits, a[0])
+	}
+	digitB := len(rankDigits)
+	if b != "" {
+		digitB = strings.IndexByte(rankDigits, b[0])
+	}
+
+	if digitB-digitA > 1 {
+		return string(rankDigits[(digitA+digitB+1)/2])
+	}
+	// Соседние цифры: если b длиннее одной цифры, подходит её первая цифра,
+	// иначе берём цифру a и продолжаем в следующем разряде без верхней границы
+	if len(b) > 1 {
+		return b[:1]
+	}
+	rest := ""
+	if a != "" {
+		rest = a[1:]
+	}
+	return string(rankDigits[digitA]) + rankBetween(rest, "")
+}
+
+func rankDigitAt(s string, i int) byte {
+	if i < len(s) {
+		return s[i]
+	}
+	return rankDigits[0]
+}
+
+// evenRanks возвращает n возрастающих рангов, равномерно распределённых
+// по самой короткой длине, в которой они помещаются
+func evenRanks(n int) []string {
+	base := int64(len(rankDigits))
+	width, space := 1, base
+	for space <= int64(n) {
+		width++
+		space *= base
+	}
+
+	ranks := make([]string, n)
+	digits := make([]byte, width)
+	for i := range ranks {
+		v := int64(i+1) * space / int64(n+1)
+		for j := width - 1; j >= 0; j-- {
+			digits[j] = rankDigits[v%base]
+			v /= base
+		}
+		ranks[i] = strings.TrimRight(string(digits), rankDigits[:1])
+	}
+	return ranks
+}
+
+// rankedTask - задача дня с её рангом
+type rankedTask struct {
+	id   int64
+	rank string
+}
+
+// ReorderTask ставит задачу id сразу после задачи after или сразу перед
+// задачей before (указывается одна из них) внутри той же даты.
+// Обычно меняется только ранг самой задачи. Если в дне есть задачи без
+// ранга, им один раз назначаются ранги в текущем порядке, а если ранг
+// становится длиннее maxRankLength, ранги дня назначаются заново. Каждая
+// задача с новым рангом получает новую версию и запись в журнале изменений.
+func ReorderTask(ctx context.Context, id, before, after int64) error {
+	neighbour := after
+	if neighbour == 0 {
+		neighbour = before
+	}
+	if neighbour == 0 || neighbour == id || (before != 0 && after != 0) {
+		return ErrInvalidReorder
+	}
+
+	return inTx(func(tx *sql.Tx) error {
+		var date, neighbourDate string
+		err := tx.QueryRow(`SELECT date FROM scheduler WHERE id = ? AND deleted_at IS NULL`, id).Scan(&date)
+		if err == nil {
+			err = tx.QueryRow(`SELECT date FROM scheduler WHERE id = ? AND deleted_at IS NULL`, neighbour).
+				Scan(&neighbourDate)
+		}
+		if err == sql.ErrNoRows {
+			return ErrNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read task: %w", err)
+		}
+		if date != neighbourDate {
+			return ErrInvalidReorder
+		}
+
+		day, err := rankDay(ctx, tx, date, id)
+		if err != nil {
+			return err
+		}
+
+		pos := -1
+		for i, t := range day {
+			if t.id == neighbour {
+				pos = i
+				break
+			}
+		}
+
+		// Ранги соседей по новой позиции: lower идёт перед задачей, upper - после
+		if after != 0 {
+			pos++
+		}
+		var lower, upper string
+		if pos > 0 {
+			lower = day[pos-1].rank
+		}
+		if pos < len(day) {
+			upper = day[pos].rank
+		}
+
+		if rank := rankBetween(lower, upper); len(rank) <= maxRankLength {
+			return setRank(ctx, tx, id, rank)
+		}
+		day = slices.Insert(day, pos, rankedTask{id: id})
+		return assignRanks(ctx, tx, day, evenRanks(len(day)))
+	})
+}
+
+// rankDay возвращает задачи дня, кроме exclude, в порядке списка,
+// назначив ранги тем, у кого их нет. Если ранги задач совпадают
+// (например, задача перенесена из другого дня при откате) или новые ранги
+// получаются длиннее maxRankLength, ранги всего дня назначаются заново.
+func rankDay(ctx context.Context, tx *sql.Tx, date string, exclude int64) ([]rankedTask, error) {
+	rows, err := tx.Query(`SELECT id, rank FROM scheduler 
+	                       WHERE date = ? AND deleted_at IS NULL AND id <> ? 
+	                       ORDER BY rank = '', rank, priority, id`, date, exclude)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load tasks of the day: %w", err)
+	}
+	defer rows.Close()
+
+	var day []rankedTask
+	for rows.Next() {
+		var t rankedTask
+		if err := rows.Scan(&t.id, &t.rank); err != nil {
+			return nil, fmt.Errorf("row scan error: %w", err)
+		}
+		day = append(day, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	rows.Close()
+
+	renumber := false
+	for i := 1; i < len(day); i++ {
+		if day[i].rank != "" && day[i].rank <= day[i-1].rank {
+			renumber = true
+			break
+		}
+	}
+
+	ranks := make([]string, len(day))
+	last := ""
+	for i := range day {
+		if day[i].rank == "" {
+			last = rankBetween(last, "")
+		} else {
+			last = day[i].rank
+		}
+		ranks[i] = last
+		if len(last) > maxRankLength {
+			renumber = true
+		}
+	}
+	if renumber {
+		ranks = evenRanks(len(day))
+	}
+
+	if err := assignRanks(ctx, tx, day, ranks); err != nil {
+		return nil, err
+	}
+	return day, nil
+}
+
+// assignRanks назначает задачам day ранги ranks, сохраняя только изменившиеся
+func assignRanks(ctx context.Context, tx *sql.Tx, day []rankedTask, ranks []string) error {
+	for i := range day {
+		if day[i].rank == ranks[i] {
+			continue
+		}
+		if err := setRank(ctx, tx, day[i].id, ranks[i]); err != nil {
+			return err
+		}
+		day[i].rank = ranks[i]
+	}
+	return nil
+}
+
+// setRank меняет ранг задачи, увеличивая её версию, и записывает изменение в журнал
+func setRank(ctx context.Context, tx *sql.Tx, id int64, rank string) error {
+	before, err := snapshotTask(tx, id)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`UPDATE scheduler SET rank = ?, version = version + 1 WHERE id = ?`, rank, id)
+	if err != nil {
+		return fmt.Errorf("failed to rank task: %w", err)
+	}
+	return recordChange(ctx, tx, id, AuditReorder, before)
+}
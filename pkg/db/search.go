@@ -0,0 +1,50 @@
+package db
+
+import (
+	"html"
+	"strings"
+)
+
+// Теги, которыми выделяются найденные слова в Highlight
+const (
+	highlightOpen  = "<mark>"
+	highlightClose = "</mark>"
+)
+
+// Маркеры совпадений, которые расставляет FTS5. Это управляющие символы,
+// которых нет в тексте задач: текст сначала экранируется для HTML, и только
+// потом маркеры заменяются тегами, чтобы разметка из задачи не попала в ответ.
+const (
+	matchOpen  = "\x02"
+	matchClose = "\x03"
+)
+
+var highlightTags = strings.NewReplacer(matchOpen, highlightOpen, matchClose, highlightClose)
+
+// Highlight содержит заголовок и фрагмент комментария с выделенными
+// совпадениями полнотекстового поиска. Текст экранирован для HTML.
+type Highlight struct {
+	Title   string `json:"title"`
+	Comment string `json:"comment"`
+}
+
+// ftsQuery превращает пользовательскую строку поиска в выражение FTS5:
+// каждое слово ищется как префикс, все слова должны встретиться в задаче.
+// Если в строке нет ни одного слова, возвращается пустая строка.
+func ftsQuery(search string) string {
+	words := searchWords(search)
+
+	terms := make([]string, 0, len(words))
+	for _, word := range words {
+		// Слова состоят только из букв и цифр, поэтому кавычки
+		// экранируют операторы FTS5 вроде AND, OR и NOT
+		terms = append(terms, `"`+word+`"*`)
+	}
+	return strings.Join(terms, " ")
+}
+
+// highlightHTML экранирует текст с маркерами совпадений для HTML
+// и заменяет маркеры тегами выделения
+func highlightHTML(text string) string {
+	return highlightTags.Replace(html.EscapeString(text))
+}
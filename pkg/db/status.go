@@ -0,0 +1,100 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// Статусы задачи
+const (
+	StatusTodo       = "todo"
+	StatusInProgress = "in_progress"
+	StatusDone       = "done"
+	StatusCancelled  = "cancelled"
+)
+
+// Statuses - все статусы в порядке колонок доски
+var Statuses = []string{StatusTodo, StatusInProgress, StatusDone, StatusCancelled}
+
+// activeStatuses - статусы, которые список задач показывает по умолчанию
+var activeStatuses = []string{StatusTodo, StatusInProgress}
+
+var (
+	// ErrInvalidStatus возвращается для неизвестного статуса
+	ErrInvalidStatus = errors.New("invalid status, expected todo, in_progress, done or cancelled")
+	// ErrInvalidTransition возвращается, если из текущего статуса нельзя
+	// перейти в запрошенный
+	ErrInvalidTransition = errors.New("status transition is not allowed")
+)
+
+// transitions - разрешённые переходы между статусами. Выполненную или
+// отменённую задачу можно только вернуть в работу через todo.
+var transitions = map[string][]string{
+	StatusTodo:       {StatusInProgress, StatusDone, StatusCancelled},
+	StatusInProgress: {StatusTodo, StatusDone, StatusCancelled},
+	StatusDone:       {StatusTodo},
+	StatusCancelled:  {StatusTodo},
+}
+
+// CheckStatus проверяет, что статус известен
+func CheckStatus(status string) error {
+	if !slices.Contains(Statuses, status) {
+		return fmt.Errorf("%w: %q", ErrInvalidStatus, status)
+	}
+	return nil
+}
+
+func checkTransition(from, to string) error {
+	if from == to || slices.Contains(transitions[from], to) {
+		return nil
+	}
+	return fmt.Errorf("%w: %s -> %s", ErrInvalidTransition, from, to)
+}
+
+// SetTaskStatus меняет статус задачи. Перевод в done выполняет CompleteTask,
+// поэтому здесь он не принимается. При возврате в todo отметка
+// о выполнении снимается.
+func SetTaskStatus(ctx context.Context, id int64, status string) error {
+	if err := CheckStatus(status); err != nil {
+		return err
+	}
+	if status == StatusDone {
+		return fmt.Errorf("%w: use task completion to mark a task done", ErrInvalidTransition)
+	}
+
+	return inTx(func(tx *sql.Tx) error {
+		var current string
+		err := tx.QueryRow(`SELECT status FROM scheduler WHERE id = ? AND deleted_at IS NULL`, id).Scan(&current)
+		if err == sql.ErrNoRows {
+			return ErrNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read task status: %w", err)
+		}
+
+		if current == status {
+			return nil
+		}
+		if err := checkTransition(current, status); err != nil {
+			return err
+		}
+
+		before, err := snapshotTask(tx, id)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(`UPDATE scheduler 
+		                  SET status = ?, completed_at = CASE WHEN ? = 'todo' THEN NULL ELSE completed_at END, 
+		                      version = version + 1 
+		                  WHERE id = ?`, status, status, id)
+		if err != nil {
+			return fmt.Errorf("failed to update task status: %w", err)
+		}
+
+		return recordChange(ctx, tx, id, AuditStatus, before)
+	})
+}
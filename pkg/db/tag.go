@@ -0,0 +1,269 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+const maxTagLength = 64
+
+var (
+	// ErrInvalidTag возвращается для пустого или слишком длинного имени метки
+	ErrInvalidTag = errors.New("invalid tag name")
+	// ErrTagExists возвращается при попытке создать метку с занятым именем
+	ErrTagExists = errors.New("tag already exists")
+	// ErrTagNotFound возвращается, если метки с указанным ID нет
+	ErrTagNotFound = errors.New("tag not found")
+)
+
+// Tag - метка задач с числом задач, которые ей отмечены
+type Tag struct {
+	ID    int64  `json:"id"`
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// normalizeTag приводит имя метки к виду хранения: без пробелов по краям,
+// в нижнем регистре. Запятая не допускается, так как разделяет метки в фильтре.
+func normalizeTag(name string) (string, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" || utf8.RuneCountInString(name) > maxTagLength || strings.Contains(name, ",") {
+		return "", fmt.Errorf("%w: %q", ErrInvalidTag, name)
+	}
+	return name, nil
+}
+
+// normalizeTags нормализует список меток, убирая повторы
+func normalizeTags(names []string) ([]string, error) {
+	result := make([]string, 0, len(names))
+	for _, name := range names {
+		tag, err := normalizeTag(name)
+		if err != nil {
+			return nil, err
+		}
+		if !slices.Contains(result, tag) {
+			result = append(result, tag)
+		}
+	}
+	slices.Sort(result)
+	return result, nil
+}
+
+// setTaskTags заменяет метки задачи, создавая недостающие
+func setTaskTags(q querier, taskID int64, names []string) error {
+	tags, err := normalizeTags(names)
+	if err != nil {
+		return err
+	}
+
+	if _, err := q.Exec(`DELETE FROM task_tags WHERE task_id = ?`, taskID); err != nil {
+		return fmt.Errorf("failed to clear task tags: %w", err)
+	}
+
+	for _, tag := range tags {
+		if _, err := q.Exec(`INSERT INTO tags (name) VALUES (?) ON CONFLICT(name) DO NOTHING`, tag); err != nil {
+			return fmt.Errorf("failed to create tag: %w", err)
+		}
+		if _, err := q.Exec(`INSERT INTO task_tags (task_id, tag_id) 
+		                     SELECT ?, id FROM tags WHERE name = ?`, taskID, tag); err != nil {
+			return fmt.Errorf("failed to tag task: %w", err)
+		}
+	}
+	return nil
+}
+
+// loadTags заполняет метки у списка задач одним запросом
+func loadTags(q querier, tasks []*Task) error {
+	if len(tasks) == 0 {
+		return nil
+	}
+
+	byID := make(map[int64]*Task, len(tasks))
+	placeholders := make([]string, len(tasks))
+	args := make([]interface{}, len(tasks))
+	for i, task := range tasks {
+		task.Tags = make([]string, 0)
+		byID[task.ID] = task
+		placeholders[i] = "?"
+		args[i] = task.ID
+	}
+
+	rows, err := q.Query(`SELECT tt.task_id, t.name 
+	                      FROM task_tags tt 
+	                      JOIN tags t ON t.id = tt.tag_id 
+	                      WHERE tt.task_id IN (`+strings.Join(placeholders, ", ")+`) 
+	                      ORDER BY t.name`, args...)
+	if err != nil {
+		return fmt.Errorf("failed to load tags: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var taskID int64
+		var name string
+		if err := rows.Scan(&taskID, &name); err != nil {
+			return fmt.Errorf("row scan error: %w", err)
+		}
+		if task, ok := byID[taskID]; ok {
+			task.Tags = append(task.Tags, name)
+		}
+	}
+	return rows.Err()
+}
+
+// Tags возвращает все метки с числом задач вне корзины
+func Tags() ([]*Tag, error) {
+	rows, err := DB.Query(`SELECT t.id, t.name, COUNT(s.id) 
+	                       FROM tags t 
+	                       LEFT JOIN task_tags tt ON tt.tag_id = t.id 
+	                       LEFT JOIN scheduler s ON s.id = tt.task_id AND s.deleted_at IS NULL 
+	                       GROUP BY t.id 
+	                       ORDER BY t.name`)
+	if err != nil {
+		return nil, fmt.Errorf("database query error: %w", err)
+	}
+	defer rows.Close()
+
+	tags := make([]*Tag, 0)
+	for rows.Next() {
+		var tag Tag
+		if err := rows.Scan(&tag.ID, &tag.Name, &tag.Count); err != nil {
+			return nil, fmt.Errorf("row scan error: %w", err)
+		}
+		tags = append(tags, &tag)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return tags, nil
+}
+
+// AddTag создаёт метку
+func AddTag(name string) (int64, error) {
+	name, err := normalizeTag(name)
+	if err != nil {
+		return 0, err
+	}
+
+	res, err := DB.Exec(`INSERT INTO tags (name) VALUES (?) ON CONFLICT(name) DO NOTHING`, name)
+	if err != nil {
+		return 0, fmt.Errorf("failed to insert tag: %w", err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return 0, ErrTagExists
+	}
+
+	return res.LastInsertId()
+}
+
+// RenameTag меняет имя метки. Изменение попадает в журнал каждой
+// отмеченной ей задачи.
+func RenameTag(ctx context.Context, id int64, name string) error {
+	name, err := normalizeTag(name)
+	if err != nil {
+		return err
+	}
+
+	var exists bool
+	err = DB.QueryRow(`SELECT EXISTS(SELECT 1 FROM tags WHERE name = ? AND id <> ?)`, name, id).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to check tag: %w", err)
+	}
+	if exists {
+		return ErrTagExists
+	}
+
+	return changeTag(ctx, id, func(tx *sql.Tx) error {
+		res, err := tx.Exec(`UPDATE tags SET name = ? WHERE id = ?`, name, id)
+		if err != nil {
+			return fmt.Errorf("failed to rename tag: %w", err)
+		}
+		return checkTagFound(res)
+	})
+}
+
+// DeleteTag удаляет метку и снимает её со всех задач. Изменение попадает
+// в журнал каждой задачи, с которой снята метка.
+func DeleteTag(ctx context.Context, id int64) error {
+	return changeTag(ctx, id, func(tx *sql.Tx) error {
+		res, err := tx.Exec(`DELETE FROM tags WHERE id = ?`, id)
+		if err != nil {
+			return fmt.Errorf("failed to delete tag: %w", err)
+		}
+		return checkTagFound(res)
+	})
+}
+
+// changeTag выполняет change над меткой tagID в транзакции, увеличивая
+// версию отмеченных ей задач, включая задачи в корзине, и записывая
+// их изменение в журнал
+func changeTag(ctx context.Context, tagID int64, change func(tx *sql.Tx) error) error {
+	return inTx(func(tx *sql.Tx) error {
+		rows, err := tx.Query(`SELECT task_id FROM task_tags WHERE tag_id = ? ORDER BY task_id`, tagID)
+		if err != nil {
+			return fmt.Errorf("failed to list tagged tasks: %w", err)
+		}
+		var ids []int64
+		for rows.Next() {
+			var id int64
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				return fmt.Errorf("row scan error: %w", err)
+			}
+			ids = append(ids, id)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("rows error: %w", err)
+		}
+
+		before := make([][]byte, len(ids))
+		for i, id := range ids {
+			if before[i], err = snapshotTask(tx, id); err != nil {
+				return err
+			}
+		}
+
+		_, err = tx.Exec(`UPDATE scheduler SET version = version + 1 
+		                  WHERE id IN (SELECT task_id FROM task_tags WHERE tag_id = ?)`, tagID)
+		if err != nil {
+			return fmt.Errorf("failed to update tagged tasks: %w", err)
+		}
+		if err := change(tx); err != nil {
+			return err
+		}
+
+		for i, id := range ids {
+			if err := recordChange(ctx, tx, id, AuditUpdate, before[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func checkTagFound(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if n == 0 {
+		return ErrTagNotFound
+	}
+	return nil
+}
+
+// tagFilter - условие "задача отмечена меткой name"
+func tagFilter(name string) condNode {
+	return condNode{
+		cond: `s.id IN (SELECT tt.task_id FROM task_tags tt 
+		                JOIN tags t ON t.id = tt.tag_id WHERE t.name = ?)`,
+		args: []interface{}{name},
+	}
+}
@@ -1,176 +1,701 @@
 package db
 
 import (
+	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
-	"strings"
 	"time"
 )
 
+var (
+	// ErrNotFound возвращается, если задачи с указанным ID нет
+	ErrNotFound = errors.New("task not found")
+	// ErrConflict возвращается, если задача изменилась между чтением и записью
+	ErrConflict = errors.New("task was modified concurrently")
+	// ErrVersionMismatch возвращается, если версия задачи не совпала с ожидаемой
+	ErrVersionMismatch = errors.New("task version mismatch")
+	// ErrInvalidPriority возвращается для приоритета вне диапазона P1-P4
+	ErrInvalidPriority = errors.New("invalid priority, expected 1-4")
+)
+
+// Приоритеты задачи: P1 - самый срочный, P4 - приоритет по умолчанию
+const (
+	PriorityHighest = 1
+	PriorityLowest  = 4
+)
+
 // Task представляет структуру задачи
 type Task struct {
-	ID      int64  `json:"id"`
-	Date    string `json:"date"`
+	ID   int64  `json:"id"`
+	Date string `json:"date"`
+	// Due - срок выполнения в формате YYYYMMDD, пусто - без срока.
+	// Date - дата, на которую задача запланирована. При обновлении срок
+	// меняется, только если DueSet.
+	Due     string `json:"due,omitempty"`
 	Title   string `json:"title"`
 	Comment string `json:"comment"`
 	Repeat  string `json:"repeat"`
+	// ProjectID - проект задачи, 0 при добавлении означает входящие
+	ProjectID int64 `json:"project_id"`
+	// Priority - от 1 (P1) до 4 (P4), 0 при добавлении означает P4,
+	// при обновлении - оставить текущий
+	Priority int `json:"priority"`
+	// Status - StatusTodo, StatusInProgress, StatusDone или StatusCancelled.
+	// При обновлении задачи не меняется, см. SetTaskStatus и CompleteTask.
+	Status string `json:"status"`
+	// CompletedAt - момент выполнения, заполняется у выполненных одноразовых задач
+	CompletedAt string `json:"completed_at,omitempty"`
+	// DueSet - срок передан клиентом, выставляется при разборе JSON
+	DueSet bool `json:"-"`
+	// Overdue - срок выполнения уже прошёл, заполняется при чтении
+	Overdue bool `json:"overdue,omitempty"`
+	// Rank - ручной порядок задачи внутри дня, заполняется только в снимках журнала
+	Rank string `json:"rank,omitempty"`
+	// Version увеличивается при каждом изменении и отдаётся клиенту в ETag
+	Version int64    `json:"-"`
+	Tags    []string `json:"tags"`
+	// Checklist - прогресс чек-листа, пусто у задач без пунктов
+	Checklist *ChecklistProgress `json:"checklist,omitempty"`
+	// BlockedBy - задачи, от которых зависит эта, Blocks - зависящие от неё.
+	// Blocked означает, что среди BlockedBy есть невыполненные.
+	BlockedBy []int64 `json:"blocked_by,omitempty"`
+	Blocks    []int64 `json:"blocks,omitempty"`
+	Blocked   bool    `json:"blocked,omitempty"`
+	// Links - задачи, упомянутые в комментарии как #id, Backlinks - задачи,
+	// упоминающие эту. Заполняются только при чтении одной задачи.
+	Links     []TaskLink `json:"links,omitempty"`
+	Backlinks []TaskLink `json:"backlinks,omitempty"`
+	// DeletedAt заполняется только для задач из корзины
+	DeletedAt string `json:"deleted_at,omitempty"`
+	// Highlight заполняется при полнотекстовом поиске
+	Highlight *Highlight `json:"highlight,omitempty"`
 }
 
-// AddTask добавляет новую задачу в базу данных
-func AddTask(task *Task) (int64, error) {
-	query := `INSERT INTO scheduler (date, title, comment, repeat) 
-	          VALUES (?, ?, ?, ?)`
+// UnmarshalJSON разбирает задачу, отмечая в DueSet, было ли передано поле due
+func (t *Task) UnmarshalJSON(data []byte) error {
+	type plainTask Task
+	fields := struct {
+		*plainTask
+		Due *string `json:"due"`
+	}{plainTask: (*plainTask)(t)}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
 
-	res, err := DB.Exec(query, task.Date, task.Title, task.Comment, task.Repeat)
-	if err != nil {
-		return 0, fmt.Errorf("failed to insert task: %w", err)
+	t.DueSet = fields.Due != nil
+	if t.DueSet {
+		t.Due = *fields.Due
 	}
+	return nil
+}
 
-	id, err := res.LastInsertId()
+const (
+	addTaskQuery = `INSERT INTO scheduler (date, due, title, comment, repeat, project_id, priority, status) 
+	                VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+
+	getTaskQuery = `SELECT id, date, due, title, comment, repeat, project_id, priority, status, 
+	                       COALESCE(completed_at, ''), version 
+	                FROM scheduler 
+	                WHERE id = ? AND deleted_at IS NULL`
+
+	// При переносе на другую дату ручной порядок задачи сбрасывается.
+	// NULL вместо срока оставляет текущий.
+	updateTaskQuery = `UPDATE scheduler 
+	                   SET rank = CASE WHEN date = ?1 THEN rank ELSE '' END, 
+	                       date = ?1, due = COALESCE(?, due), title = ?, comment = ?, repeat = ?, 
+	                       project_id = COALESCE(NULLIF(?, 0), project_id), 
+	                       priority = COALESCE(NULLIF(?, 0), priority), version = version + 1 
+	                   WHERE id = ? AND deleted_at IS NULL AND (? = 0 OR version = ?) 
+	                   RETURNING version`
+
+	deleteTaskQuery = `UPDATE scheduler 
+	                   SET deleted_at = ?, version = version + 1 
+	                   WHERE id = ? AND deleted_at IS NULL AND (? = 0 OR version = ?)`
+)
+
+// stmts хранит подготовленные выражения для часто выполняемых запросов
+var stmts struct {
+	addTask    *sql.Stmt
+	getTask    *sql.Stmt
+	updateTask *sql.Stmt
+	deleteTask *sql.Stmt
+}
+
+// prepareStatements один раз подготавливает запросы к таблице задач
+func prepareStatements(db *sql.DB) error {
+	list := []struct {
+		dst   **sql.Stmt
+		query string
+	}{
+		{&stmts.addTask, addTaskQuery},
+		{&stmts.getTask, getTaskQuery},
+		{&stmts.updateTask, updateTaskQuery},
+		{&stmts.deleteTask, deleteTaskQuery},
+	}
+
+	for _, item := range list {
+		stmt, err := db.Prepare(item.query)
+		if err != nil {
+			closeStatements()
+			return fmt.Errorf("failed to prepare statement: %w", err)
+		}
+		*item.dst = stmt
+	}
+	return nil
+}
+
+// closeStatements освобождает подготовленные выражения
+func closeStatements() {
+	for _, stmt := range []**sql.Stmt{
+		&stmts.addTask, &stmts.getTask, &stmts.updateTask, &stmts.deleteTask,
+	} {
+		if *stmt != nil {
+			(*stmt).Close()
+			*stmt = nil
+		}
+	}
+}
+
+// AddTask добавляет новую задачу в базу данных
+func AddTask(ctx context.Context, task *Task) (int64, error) {
+	var id int64
+	err := inTx(func(tx *sql.Tx) error {
+		var err error
+		id, err = addTask(ctx, tx, task)
+		return err
+	})
 	if err != nil {
-		return 0, fmt.Errorf("failed to get last insert ID: %w", err)
+		return 0, err
 	}
 
 	return id, nil
 }
 
-// Tasks возвращает список задач с поддержкой поиска
-func Tasks(limit int, search string) ([]*Task, error) {
-	var query string
-	var args []interface{}
+// AddTaskOptions - проверки при добавлении задачи клиентом
+type AddTaskOptions struct {
+	// IdempotencyKey - ключ идемпотентности запроса, пусто - без ключа.
+	// RequestHash - хэш запроса для сравнения повторов с тем же ключом.
+	IdempotencyKey string
+	RequestHash    string
+	// SkipDuplicates отключает поиск похожих открытых задач
+	SkipDuplicates bool
+	// Response формирует ответ клиенту, который сохраняется вместе с ключом
+	// идемпотентности и отдаётся повторным запросам как есть
+	Response func(res AddTaskResult) (status int, body []byte, err error)
+}
 
-	switch {
-	case isDateSearch(search):
-		date, err := parseSearchDate(search)
-		if err != nil {
-			return nil, fmt.Errorf("invalid date format: %w", err)
+// AddTaskResult - результат AddTaskWithOptions
+type AddTaskResult struct {
+	ID int64
+	// Replayed - задача добавлена ранее запросом с тем же ключом идемпотентности
+	Replayed bool
+	// Duplicates - похожие открытые задачи, найденные при добавлении
+	Duplicates []int64
+	// Status и Response - сохранённый ответ на исходный запрос при Replayed
+	Status   int
+	Response []byte
+}
+
+// AddTaskWithOptions добавляет задачу, как AddTask, с дополнительными
+// проверками. Повторный запрос с тем же ключом идемпотентности в пределах
+// срока хранения не добавляет задачу, а возвращает ID добавленной ранее
+// и сохранённый ответ с Replayed = true; тот же ключ с другим запросом
+// даёт ErrIdempotencyMismatch.
+// Похожие открытые задачи не мешают добавлению и возвращаются в Duplicates
+// как предупреждение, если не указан SkipDuplicates.
+func AddTaskWithOptions(ctx context.Context, task *Task, opts AddTaskOptions) (AddTaskResult, error) {
+	useKey := opts.IdempotencyKey != "" && IdempotencyEnabled()
+
+	var res AddTaskResult
+	err := inTx(func(tx *sql.Tx) error {
+		var err error
+		if useKey {
+			if res, err = idempotentResult(tx, opts.IdempotencyKey, opts.RequestHash); err != nil || res.Replayed {
+				return err
+			}
+		}
+
+		if !opts.SkipDuplicates {
+			if res.Duplicates, err = findDuplicates(tx, task); err != nil {
+				return err
+			}
 		}
-		query = `SELECT id, date, title, comment, repeat 
-		         FROM scheduler 
-		         WHERE date = ? 
-		         ORDER BY date ASC 
-		         LIMIT ?`
-		args = []interface{}{date, limit}
 
-	case search != "":
-		searchTerm := "%" + strings.ToLower(search) + "%"
-		query = `SELECT id, date, title, comment, repeat 
-		         FROM scheduler 
-		         WHERE LOWER(title) LIKE ? OR LOWER(comment) LIKE ? 
-		         ORDER BY date ASC 
-		         LIMIT ?`
-		args = []interface{}{searchTerm, searchTerm, limit}
+		if res.ID, err = addTask(ctx, tx, task); err != nil {
+			return err
+		}
 
-	default:
-		query = `SELECT id, date, title, comment, repeat 
-		         FROM scheduler 
-		         ORDER BY date ASC 
-		         LIMIT ?`
-		args = []interface{}{limit}
+		if useKey {
+			var body []byte
+			if opts.Response != nil {
+				if res.Status, body, err = opts.Response(res); err != nil {
+					return err
+				}
+			}
+			return storeIdempotencyKey(tx, opts.IdempotencyKey, opts.RequestHash, res.ID, res.Status, body)
+		}
+		return nil
+	})
+	if err != nil {
+		return AddTaskResult{}, err
 	}
+	return res, nil
+}
 
-	rows, err := DB.Query(query, args...)
+// addTask проверяет и добавляет задачу в транзакции tx
+func addTask(ctx context.Context, tx *sql.Tx, task *Task) (int64, error) {
+	if task.Priority == 0 {
+		task.Priority = PriorityLowest
+	}
+	if err := checkPriority(task.Priority); err != nil {
+		return 0, err
+	}
+	// Новая задача может сразу быть в работе, но не выполненной
+	if task.Status == "" {
+		task.Status = StatusTodo
+	}
+	if task.Status != StatusTodo && task.Status != StatusInProgress {
+		return 0, fmt.Errorf("%w: new task must be todo or in_progress", ErrInvalidStatus)
+	}
+
+	projectID, err := checkTaskProject(tx, task.ProjectID)
 	if err != nil {
-		return nil, fmt.Errorf("database query error: %w", err)
+		return 0, err
 	}
-	defer rows.Close()
+	task.ProjectID = projectID
 
-	var tasks []*Task
-	for rows.Next() {
-		var task Task
-		if err := rows.Scan(&task.ID, &task.Date, &task.Title,
-			&task.Comment, &task.Repeat); err != nil {
-			return nil, fmt.Errorf("row scan error: %w", err)
-		}
-		tasks = append(tasks, &task)
+	res, err := tx.Stmt(stmts.addTask).Exec(task.Date, task.Due, task.Title, task.Comment,
+		task.Repeat, task.ProjectID, task.Priority, task.Status)
+	if err != nil {
+		return 0, fmt.Errorf("failed to insert task: %w", err)
 	}
 
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("rows error: %w", err)
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get last insert ID: %w", err)
 	}
 
-	// Гарантируем возврат [] вместо nil
-	if tasks == nil {
-		tasks = make([]*Task, 0)
+	if err := setTaskTags(tx, id, task.Tags); err != nil {
+		return 0, err
+	}
+	if err := setTaskLinks(tx, id, task.Comment); err != nil {
+		return 0, err
 	}
 
-	return tasks, nil
+	return id, recordChange(ctx, tx, id, AuditInsert, nil)
 }
 
-// isDateSearch проверяет, является ли строка датой в формате dd.mm.yyyy
-func isDateSearch(s string) bool {
-	_, err := time.Parse("02.01.2006", s)
-	return err == nil
+const (
+	// storageDateFormat - формат хранения даты задачи
+	storageDateFormat = "20060102"
+	// searchDateFormat - формат даты в строке поиска
+	searchDateFormat = "02.01.2006"
+)
+
+// checkPriority проверяет, что приоритет в диапазоне P1-P4
+func checkPriority(priority int) error {
+	if priority < PriorityHighest || priority > PriorityLowest {
+		return ErrInvalidPriority
+	}
+	return nil
+}
+
+// DeleteTask перемещает задачу в корзину. Если version не равна нулю, задача
+// удаляется только при совпадении версии, иначе возвращается ErrVersionMismatch.
+func DeleteTask(ctx context.Context, id int64, version int64) error {
+	return inTx(func(tx *sql.Tx) error {
+		return deleteTask(ctx, tx, id, version)
+	})
 }
 
-// parseSearchDate преобразует строку dd.mm.yyyy в формат yyyymmdd
-func parseSearchDate(s string) (string, error) {
-	t, err := time.Parse("02.01.2006", s)
+func deleteTask(ctx context.Context, tx *sql.Tx, id int64, version int64) error {
+	before, err := snapshotTask(tx, id)
 	if err != nil {
-		return "", fmt.Errorf("invalid date format: %w", err)
+		return err
 	}
-	return t.Format("20060102"), nil
-}
 
-// DeleteTask удаляет задачу по ID
-func DeleteTask(id int64) error {
-	_, err := DB.Exec("DELETE FROM scheduler WHERE id = ?", id)
+	res, err := tx.Stmt(stmts.deleteTask).Exec(formatTimestamp(time.Now()), id, version, version)
 	if err != nil {
 		return fmt.Errorf("failed to delete task: %w", err)
 	}
-	return nil
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		if version == 0 {
+			return nil
+		}
+		return versionError(tx, id)
+	}
+
+	return recordChange(ctx, tx, id, AuditDelete, before)
 }
 
 // GetTask возвращает задачу по ID
 func GetTask(id int64) (*Task, error) {
 	var task Task
-	query := `SELECT id, date, title, comment, repeat 
-	          FROM scheduler 
-	          WHERE id = ?`
-
-	err := DB.QueryRow(query, id).Scan(
+	err := stmts.getTask.QueryRow(id).Scan(
 		&task.ID,
 		&task.Date,
+		&task.Due,
 		&task.Title,
 		&task.Comment,
 		&task.Repeat,
+		&task.ProjectID,
+		&task.Priority,
+		&task.Status,
+		&task.CompletedAt,
+		&task.Version,
 	)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("task not found")
+			return nil, ErrNotFound
 		}
 		return nil, fmt.Errorf("failed to get task: %w", err)
 	}
+	markOverdue([]*Task{&task}, time.Now())
+
+	if err := loadTags(DB, []*Task{&task}); err != nil {
+		return nil, err
+	}
+	if err := loadChecklistProgress(DB, []*Task{&task}); err != nil {
+		return nil, err
+	}
+	if err := loadDependencies(DB, []*Task{&task}); err != nil {
+		return nil, err
+	}
+	if err := loadLinks(DB, &task); err != nil {
+		return nil, err
+	}
 
 	return &task, nil
 }
 
-// UpdateTask обновляет существующую задачу
-func UpdateTask(task *Task) error {
-	query := `UPDATE scheduler 
-	          SET date = ?, title = ?, comment = ?, repeat = ? 
-	          WHERE id = ?`
+// UpdateTask обновляет существующую задачу. Если task.Version не равна нулю,
+// обновление выполняется только при совпадении версии. После успешного
+// обновления task.Version содержит новую версию задачи.
+func UpdateTask(ctx context.Context, task *Task) error {
+	return inTx(func(tx *sql.Tx) error {
+		return updateTask(ctx, tx, task)
+	})
+}
+
+func updateTask(ctx context.Context, tx *sql.Tx, task *Task) error {
+	if task.Priority != 0 {
+		if err := checkPriority(task.Priority); err != nil {
+			return err
+		}
+	}
+
+	before, err := snapshotTask(tx, task.ID)
+	if err != nil {
+		return err
+	}
 
-	res, err := DB.Exec(query,
+	// Нулевой проект оставляет задачу в текущем
+	if task.ProjectID != 0 {
+		if err := checkMoveTarget(tx, task.ID, task.ProjectID); err != nil {
+			return err
+		}
+	}
+
+	err = tx.Stmt(stmts.updateTask).QueryRow(
 		task.Date,
+		sql.NullString{String: task.Due, Valid: task.DueSet},
 		task.Title,
 		task.Comment,
 		task.Repeat,
+		task.ProjectID,
+		task.Priority,
 		task.ID,
-	)
+		task.Version,
+		task.Version,
+	).Scan(&task.Version)
+
+	if err == sql.ErrNoRows {
+		if task.Version == 0 {
+			return ErrNotFound
+		}
+		return versionError(tx, task.ID)
+	}
 	if err != nil {
 		return fmt.Errorf("failed to update task: %w", err)
 	}
 
+	if err := setTaskLinks(tx, task.ID, task.Comment); err != nil {
+		return err
+	}
+
+	// Метки заменяются, только если клиент их передал
+	if task.Tags != nil {
+		if err := setTaskTags(tx, task.ID, task.Tags); err != nil {
+			return err
+		}
+	}
+
+	return recordChange(ctx, tx, task.ID, AuditUpdate, before)
+}
+
+// MoveTask переносит задачу в другой проект. Нулевой projectID означает входящие.
+func MoveTask(ctx context.Context, id, projectID int64) error {
+	return inTx(func(tx *sql.Tx) error {
+		before, err := snapshotTask(tx, id)
+		if err != nil {
+			return err
+		}
+
+		if projectID == 0 {
+			projectID = InboxProjectID
+		}
+		if err := checkMoveTarget(tx, id, projectID); err != nil {
+			return err
+		}
+
+		res, err := tx.Exec(`UPDATE scheduler SET project_id = ?, version = version + 1 
+		                     WHERE id = ? AND deleted_at IS NULL AND project_id <> ?`, projectID, id, projectID)
+		if err != nil {
+			return fmt.Errorf("failed to move task: %w", err)
+		}
+
+		rowsAffected, err := res.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to get rows affected: %w", err)
+		}
+		if rowsAffected == 0 {
+			// Задача уже в этом проекте или её нет
+			exists, err := taskExists(tx, id)
+			if err != nil {
+				return err
+			}
+			if !exists {
+				return ErrNotFound
+			}
+			return nil
+		}
+
+		return recordChange(ctx, tx, id, AuditMove, before)
+	})
+}
+
+// checkMoveTarget проверяет проект, в который переносится задача.
+// Оставить задачу в её текущем проекте можно, даже если он в архиве.
+func checkMoveTarget(q querier, taskID, projectID int64) error {
+	var current int64
+	err := q.QueryRow(`SELECT project_id FROM scheduler WHERE id = ?`, taskID).Scan(&current)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("failed to check task: %w", err)
+	}
+	if err == nil && current == projectID {
+		return nil
+	}
+	_, err = checkTaskProject(q, projectID)
+	return err
+}
+
+// querier - общий интерфейс *sql.DB и *sql.Tx для вспомогательных запросов
+type querier interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// taskExists проверяет наличие задачи с указанным ID вне корзины
+func taskExists(q querier, id int64) (bool, error) {
+	var exists bool
+	err := q.QueryRow(`SELECT EXISTS(SELECT 1 FROM scheduler WHERE id = ? AND deleted_at IS NULL)`,
+		id).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check task: %w", err)
+	}
+	return exists, nil
+}
+
+// versionError определяет, почему условное изменение не затронуло строк:
+// задачи нет или её версия отличается от ожидаемой
+func versionError(q querier, id int64) error {
+	exists, err := taskExists(q, id)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrNotFound
+	}
+	return ErrVersionMismatch
+}
+
+// CompleteOptions - параметры выполнения задачи
+type CompleteOptions struct {
+	// Date и Version - дата и версия задачи, которые видел клиент. Задача
+	// выполняется, только если они не изменились, пусто и 0 - без проверки.
+	Date    string
+	Version int64
+	// Note сохраняется в истории выполнения
+	Note string
+	// Force выполняет задачу, даже если открыты блокирующие её задачи
+	Force bool
+	// NextDate вычисляет дату следующего повторения периодической задачи
+	NextDate func(now time.Time, date, repeat string) (string, error)
+}
+
+// CompleteTask атомарно отмечает задачу выполненной: одноразовая задача
+// получает статус done и момент выполнения, периодическая возвращается
+// в todo с датой следующего повторения, а её срок сдвигается на столько же
+// дней (см. ShiftDue). В историю добавляется запись о выполнении.
+// Задача читается в той же транзакции: если её дата уже не равна opts.Date,
+// возвращается ErrConflict, если версия не равна opts.Version -
+// ErrVersionMismatch. Так повторное нажатие не сдвигает периодическую задачу
+// дважды. Выполненную или отменённую задачу выполнить нельзя - возвращается
+// ErrInvalidTransition. Пока открыты блокирующие задачи, возвращается
+// ErrTaskBlocked, если не указан opts.Force.
+func CompleteTask(ctx context.Context, id int64, opts CompleteOptions) error {
+	return inTx(func(tx *sql.Tx) error {
+		return completeTask(ctx, tx, id, opts)
+	})
+}
+
+// completeTask выполняет задачу id в транзакции tx, см. CompleteTask
+func completeTask(ctx context.Context, tx *sql.Tx, id int64, opts CompleteOptions) error {
+	var date, repeat string
+	var version int64
+	err := tx.QueryRow(`SELECT date, repeat, version FROM scheduler WHERE id = ? AND deleted_at IS NULL`, id).
+		Scan(&date, &repeat, &version)
+	if err == sql.ErrNoRows {
+		return ErrNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get task: %w", err)
+	}
+	if opts.Date != "" && opts.Date != date {
+		return ErrConflict
+	}
+	if opts.Version != 0 && opts.Version != version {
+		return ErrVersionMismatch
+	}
+
+	now := time.Now()
+	var next string
+	if repeat != "" {
+		if next, err = opts.NextDate(now, date, repeat); err != nil {
+			return err
+		}
+	}
+	return applyCompletion(ctx, tx, id, date, next, now, opts.Note, opts.Force)
+}
+
+// applyCompletion записывает выполнение задачи с датой date, переносящее её
+// на nextDate, пустая nextDate - одноразовая задача
+func applyCompletion(ctx context.Context, tx *sql.Tx, id int64, date, nextDate string,
+	completedAt time.Time, note string, force bool) error {
+	before, err := snapshotTask(tx, id)
+	if err != nil {
+		return err
+	}
+
+	if !force {
+		blockers, err := openBlockers(tx, id)
+		if err != nil {
+			return err
+		}
+		if len(blockers) > 0 {
+			return fmt.Errorf("%w: %v", ErrTaskBlocked, blockers)
+		}
+	}
+
+	// Запись в историю вставляется из самой задачи, поэтому она же
+	// служит проверкой, что задача не изменилась после чтения
+	res, err := tx.Exec(`INSERT INTO completions (task_id, title, scheduled_date, completed_at, note) 
+	                     SELECT id, title, date, ?, ? 
+	                     FROM scheduler 
+	                     WHERE id = ? AND date = ? AND deleted_at IS NULL 
+	                       AND status IN ('todo', 'in_progress')`,
+		formatTimestamp(completedAt), note, id, date)
+	if err != nil {
+		return fmt.Errorf("failed to record completion: %w", err)
+	}
+
 	rowsAffected, err := res.RowsAffected()
 	if err != nil {
 		return fmt.Errorf("failed to get rows affected: %w", err)
 	}
 
 	if rowsAffected == 0 {
-		return fmt.Errorf("task not found")
+		var status string
+		err := tx.QueryRow(`SELECT status FROM scheduler WHERE id = ? AND deleted_at IS NULL`, id).
+			Scan(&status)
+		if err == sql.ErrNoRows {
+			return ErrNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("failed to check task: %w", err)
+		}
+		if status == StatusDone || status == StatusCancelled {
+			return fmt.Errorf("%w: %s -> %s", ErrInvalidTransition, status, StatusDone)
+		}
+		return ErrConflict
 	}
 
-	return nil
+	if nextDate == "" {
+		_, err = tx.Exec(`UPDATE scheduler SET status = 'done', completed_at = ?, version = version + 1 
+		                  WHERE id = ?`, formatTimestamp(completedAt), id)
+	} else {
+		var due string
+		if err := tx.QueryRow(`SELECT due FROM scheduler WHERE id = ?`, id).Scan(&due); err != nil {
+			return fmt.Errorf("failed to read due date: %w", err)
+		}
+		due, err = ShiftDue(due, date, nextDate)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(`UPDATE scheduler SET date = ?, due = ?, status = 'todo', rank = '', 
+		                      version = version + 1 
+		                  WHERE id = ?`, nextDate, due, id)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to complete task: %w", err)
+	}
+
+	// Следующее повторение начинается с невыполненным чек-листом
+	if nextDate != "" {
+		if err := resetChecklist(tx, id); err != nil {
+			return err
+		}
+	}
+
+	return recordChange(ctx, tx, id, AuditComplete, before)
+}
+
+// ShiftDue сдвигает срок выполнения due на столько же дней, на сколько
+// запланированная дата переносится с from на to, чтобы при повторении
+// задачи сохранялся запас времени между ними. Пустой срок не меняется.
+func ShiftDue(due, from, to string) (string, error) {
+	if due == "" || from == to {
+		return due, nil
+	}
+
+	dates := make([]time.Time, 3)
+	for i, s := range []string{due, from, to} {
+		d, err := time.Parse(storageDateFormat, s)
+		if err != nil {
+			return "", fmt.Errorf("invalid date %q: %w", s, err)
+		}
+		dates[i] = d
+	}
+
+	return dates[0].Add(dates[2].Sub(dates[1])).Format(storageDateFormat), nil
+}
+
+// markOverdue отмечает открытые задачи, срок выполнения которых раньше
+// сегодняшнего дня в now
+func markOverdue(tasks []*Task, now time.Time) {
+	today := now.Format(storageDateFormat)
+	for _, task := range tasks {
+		task.Overdue = task.Due != "" && task.Due < today &&
+			(task.Status == StatusTodo || task.Status == StatusInProgress)
+	}
 }
@@ -0,0 +1,336 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	maxTemplateNameLength = 255
+	// maxTemplateOffset ограничивает сдвиг дат шаблона десятью годами
+	maxTemplateOffset = 3650
+)
+
+var (
+	// ErrInvalidTemplate возвращается для шаблона с пустым именем, заголовком
+	// или недопустимым сдвигом дат
+	ErrInvalidTemplate = errors.New("invalid template")
+	// ErrTemplateExists возвращается при попытке занять имя другого шаблона
+	ErrTemplateExists = errors.New("template already exists")
+	// ErrTemplateNotFound возвращается, если шаблона с указанным ID нет
+	ErrTemplateNotFound = errors.New("template not found")
+)
+
+// Template - сохранённая заготовка задачи. Заголовок и комментарий могут
+// содержать подстановки {{date}} и {{due}} - даты создаваемой задачи.
+// Даты задачи отсчитываются от опорной даты, выбранной при создании:
+// DateOffset дней до запланированной даты и DueOffset - до срока.
+type Template struct {
+	ID        int64    `json:"id"`
+	Name      string   `json:"name"`
+	Title     string   `json:"title"`
+	Comment   string   `json:"comment"`
+	Repeat    string   `json:"repeat"`
+	Tags      []string `json:"tags"`
+	ProjectID int64    `json:"project_id"`
+	Priority  int      `json:"priority"`
+	// DateOffset - сдвиг запланированной даты от опорной в днях
+	DateOffset int `json:"date_offset"`
+	// DueOffset - сдвиг срока от опорной даты в днях, nil - без срока
+	DueOffset *int `json:"due_offset"`
+}
+
+// normalizeTemplate проверяет поля шаблона и приводит метки к виду хранения
+func normalizeTemplate(t *Template) error {
+	t.Name = strings.TrimSpace(t.Name)
+	if t.Name == "" || utf8.RuneCountInString(t.Name) > maxTemplateNameLength {
+		return fmt.Errorf("%w: name must be 1-%d characters", ErrInvalidTemplate, maxTemplateNameLength)
+	}
+	if strings.TrimSpace(t.Title) == "" {
+		return fmt.Errorf("%w: title is required", ErrInvalidTemplate)
+	}
+	if t.Priority == 0 {
+		t.Priority = PriorityLowest
+	}
+	if err := checkPriority(t.Priority); err != nil {
+		return err
+	}
+	if t.ProjectID == 0 {
+		t.ProjectID = InboxProjectID
+	}
+
+	offsets := []int{t.DateOffset}
+	if t.DueOffset != nil {
+		offsets = append(offsets, *t.DueOffset)
+	}
+	for _, offset := range offsets {
+		if offset < -maxTemplateOffset || offset > maxTemplateOffset {
+			return fmt.Errorf("%w: date offsets must be within %d days", ErrInvalidTemplate, maxTemplateOffset)
+		}
+	}
+
+	tags, err := normalizeTags(t.Tags)
+	if err != nil {
+		return err
+	}
+	t.Tags = tags
+	return nil
+}
+
+// Templates возвращает все шаблоны по имени
+func Templates() ([]*Template, error) {
+	rows, err := DB.Query(`SELECT id, name, title, comment, repeat, tags, project_id, priority, 
+	                              date_offset, due_offset 
+	                       FROM templates 
+	                       ORDER BY name, id`)
+	if err != nil {
+		return nil, fmt.Errorf("database query error: %w", err)
+	}
+	defer rows.Close()
+
+	templates := make([]*Template, 0)
+	for rows.Next() {
+		t, err := scanTemplate(rows)
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, t)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return templates, nil
+}
+
+// GetTemplate возвращает шаблон по ID
+func GetTemplate(id int64) (*Template, error) {
+	row := DB.QueryRow(`SELECT id, name, title, comment, repeat, tags, project_id, priority, 
+	                           date_offset, due_offset 
+	                    FROM templates 
+	                    WHERE id = ?`, id)
+	t, err := scanTemplate(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrTemplateNotFound
+	}
+	return t, err
+}
+
+func scanTemplate(row interface{ Scan(...interface{}) error }) (*Template, error) {
+	var t Template
+	var tags string
+	var dueOffset sql.NullInt64
+	err := row.Scan(&t.ID, &t.Name, &t.Title, &t.Comment, &t.Repeat, &tags, &t.ProjectID, &t.Priority,
+		&t.DateOffset, &dueOffset)
+	if err == sql.ErrNoRows {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("row scan error: %w", err)
+	}
+
+	if err := json.Unmarshal([]byte(tags), &t.Tags); err != nil {
+		return nil, fmt.Errorf("failed to decode template tags: %w", err)
+	}
+	if dueOffset.Valid {
+		offset := int(dueOffset.Int64)
+		t.DueOffset = &offset
+	}
+	return &t, nil
+}
+
+// AddTemplate сохраняет шаблон
+func AddTemplate(t *Template) (int64, error) {
+	if err := normalizeTemplate(t); err != nil {
+		return 0, err
+	}
+	if _, err := checkTaskProject(DB, t.ProjectID); err != nil {
+		return 0, err
+	}
+	tags, err := json.Marshal(t.Tags)
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode template tags: %w", err)
+	}
+
+	res, err := DB.Exec(`INSERT INTO templates (name, title, comment, repeat, tags, project_id, priority, 
+	                                            date_offset, due_offset) 
+	                     VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?) 
+	                     ON CONFLICT(name) DO NOTHING`,
+		t.Name, t.Title, t.Comment, t.Repeat, string(tags), t.ProjectID, t.Priority, t.DateOffset, t.DueOffset)
+	if err != nil {
+		return 0, fmt.Errorf("failed to insert template: %w", err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return 0, ErrTemplateExists
+	}
+
+	return res.LastInsertId()
+}
+
+// UpdateTemplate заменяет поля шаблона
+func UpdateTemplate(t *Template) error {
+	if err := normalizeTemplate(t); err != nil {
+		return err
+	}
+	if _, err := checkTaskProject(DB, t.ProjectID); err != nil {
+		return err
+	}
+	tags, err := json.Marshal(t.Tags)
+	if err != nil {
+		return fmt.Errorf("failed to encode template tags: %w", err)
+	}
+
+	var exists bool
+	err = DB.QueryRow(`SELECT EXISTS(SELECT 1 FROM templates WHERE name = ? AND id <> ?)`, t.Name, t.ID).
+		Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to check template: %w", err)
+	}
+	if exists {
+		return ErrTemplateExists
+	}
+
+	res, err := DB.Exec(`UPDATE templates 
+	                     SET name = ?, title = ?, comment = ?, repeat = ?, tags = ?, project_id = ?, 
+	                         priority = ?, date_offset = ?, due_offset = ? 
+	                     WHERE id = ?`,
+		t.Name, t.Title, t.Comment, t.Repeat, string(tags), t.ProjectID, t.Priority, t.DateOffset, t.DueOffset,
+		t.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update template: %w", err)
+	}
+	return checkTemplateFound(res)
+}
+
+// DeleteTemplate удаляет шаблон. Созданные по нему задачи не меняются.
+func DeleteTemplate(id int64) error {
+	res, err := DB.Exec(`DELETE FROM templates WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete template: %w", err)
+	}
+	return checkTemplateFound(res)
+}
+
+func checkTemplateFound(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if n == 0 {
+		return ErrTemplateNotFound
+	}
+	return nil
+}
+
+// InstantiateTemplate создаёт задачу по шаблону с датами, отсчитанными
+// от опорной даты anchor. prepareDate, если не nil, поправляет даты задачи
+// так же, как при добавлении задачи клиентом, до подстановки дат в текст.
+func InstantiateTemplate(ctx context.Context, id int64, anchor time.Time,
+	prepareDate func(task *Task) error) (int64, error) {
+	t, err := GetTemplate(id)
+	if err != nil {
+		return 0, err
+	}
+
+	task := &Task{
+		Date:      anchor.AddDate(0, 0, t.DateOffset).Format(storageDateFormat),
+		Repeat:    t.Repeat,
+		Tags:      t.Tags,
+		ProjectID: t.ProjectID,
+		Priority:  t.Priority,
+	}
+	if t.DueOffset != nil {
+		task.Due = anchor.AddDate(0, 0, *t.DueOffset).Format(storageDateFormat)
+	}
+	if prepareDate != nil {
+		if err := prepareDate(task); err != nil {
+			return 0, err
+		}
+	}
+
+	placeholders := strings.NewReplacer(
+		"{{date}}", formatTemplateDate(task.Date),
+		"{{due}}", formatTemplateDate(task.Due),
+	)
+	task.Title = placeholders.Replace(t.Title)
+	task.Comment = placeholders.Replace(t.Comment)
+
+	return AddTask(ctx, task)
+}
+
+// formatTemplateDate переводит дату задачи в формат ДД.ММ.ГГГГ для подстановки
+func formatTemplateDate(date string) string {
+	d, err := time.Parse(storageDateFormat, date)
+	if err != nil {
+		return date
+	}
+	return d.Format(searchDateFormat)
+}
+
+// CloneTask создаёт копию задачи с её метками и невыполненным чек-листом.
+// Если date не пуст, копия планируется на эту дату, а срок сдвигается
+// на столько же дней. prepareDate, если не nil, затем поправляет даты
+// копии, как в InstantiateTemplate. Копия начинается в статусе todo.
+func CloneTask(ctx context.Context, id int64, date string, prepareDate func(task *Task) error) (int64, error) {
+	var cloneID int64
+	err := inTx(func(tx *sql.Tx) error {
+		var src Task
+		err := tx.Stmt(stmts.getTask).QueryRow(id).Scan(&src.ID, &src.Date, &src.Due, &src.Title,
+			&src.Comment, &src.Repeat, &src.ProjectID, &src.Priority, &src.Status, &src.CompletedAt,
+			&src.Version)
+		if err == sql.ErrNoRows {
+			return ErrNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get task: %w", err)
+		}
+		if err := loadTags(tx, []*Task{&src}); err != nil {
+			return err
+		}
+
+		clone := &Task{
+			Date:      src.Date,
+			Due:       src.Due,
+			Title:     src.Title,
+			Comment:   src.Comment,
+			Repeat:    src.Repeat,
+			ProjectID: src.ProjectID,
+			Priority:  src.Priority,
+			Tags:      src.Tags,
+		}
+		if date != "" {
+			clone.Due, err = ShiftDue(src.Due, src.Date, date)
+			if err != nil {
+				return err
+			}
+			clone.Date = date
+		}
+		if prepareDate != nil {
+			if err := prepareDate(clone); err != nil {
+				return err
+			}
+		}
+
+		cloneID, err = addTask(ctx, tx, clone)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(`INSERT INTO checklist_items (task_id, title, done, position) 
+		                  SELECT ?, title, 0, position FROM checklist_items WHERE task_id = ?`, cloneID, id)
+		if err != nil {
+			return fmt.Errorf("failed to copy checklist: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return cloneID, nil
+}
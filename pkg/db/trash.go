@@ -0,0 +1,218 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+)
+
+// trashPurgeInterval - период запуска фоновой очистки корзины
+const trashPurgeInterval = time.Hour
+
+// TrashedTasks возвращает страницу из limit задач корзины, начиная с удалённых
+// последними, и общее число задач в корзине. Cursor - курсор из
+// TaskPage.NextCursor предыдущей страницы, как в TaskQuery.
+func TrashedTasks(limit int, cursor string) (*TaskPage, error) {
+	page := &TaskPage{Tasks: make([]*Task, 0)}
+	err := DB.QueryRow(`SELECT COUNT(*) FROM scheduler WHERE deleted_at IS NOT NULL`).Scan(&page.Total)
+	if err != nil {
+		return nil, fmt.Errorf("database query error: %w", err)
+	}
+
+	// Курсор - момент удаления и ID последней задачи предыдущей страницы
+	seek := ""
+	args := []interface{}{}
+	if cursor != "" {
+		values, err := decodeCursor(cursor, 2)
+		if err != nil {
+			return nil, err
+		}
+		deletedAt, ok := values[0].(string)
+		id, idOK := values[1].(int64)
+		if !ok || !idOK {
+			return nil, ErrInvalidCursor
+		}
+		seek = "AND (deleted_at < ? OR (deleted_at = ? AND id < ?))"
+		args = append(args, deletedAt, deletedAt, id)
+	}
+
+	// Читаем на одну строку больше, чтобы понять, есть ли следующая страница
+	rows, err := DB.Query(`SELECT id, date, due, title, comment, repeat, project_id, priority, status, 
+	                              COALESCE(completed_at, ''), version, deleted_at 
+	                       FROM scheduler 
+	                       WHERE deleted_at IS NOT NULL `+seek+` 
+	                       ORDER BY deleted_at DESC, id DESC 
+	                       LIMIT ?`, append(args, limit+1)...)
+	if err != nil {
+		return nil, fmt.Errorf("database query error: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		if len(page.Tasks) == limit {
+			last := page.Tasks[len(page.Tasks)-1]
+			if page.NextCursor, err = encodeCursor([]interface{}{last.DeletedAt, last.ID}); err != nil {
+				return nil, err
+			}
+			break
+		}
+
+		var task Task
+		if err := rows.Scan(&task.ID, &task.Date, &task.Due, &task.Title, &task.Comment,
+			&task.Repeat, &task.ProjectID, &task.Priority, &task.Status, &task.CompletedAt,
+			&task.Version, &task.DeletedAt); err != nil {
+			return nil, fmt.Errorf("row scan error: %w", err)
+		}
+		page.Tasks = append(page.Tasks, &task)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	rows.Close()
+
+	if err := loadTags(DB, page.Tasks); err != nil {
+		return nil, err
+	}
+	if err := loadChecklistProgress(DB, page.Tasks); err != nil {
+		return nil, err
+	}
+
+	return page, nil
+}
+
+// RestoreTask возвращает задачу из корзины
+func RestoreTask(ctx context.Context, id int64) error {
+	return inTx(func(tx *sql.Tx) error {
+		before, err := snapshotTask(tx, id)
+		if err != nil {
+			return err
+		}
+
+		res, err := tx.Exec(`UPDATE scheduler 
+		                     SET deleted_at = NULL, version = version + 1 
+		                     WHERE id = ? AND deleted_at IS NOT NULL`, id)
+		if err != nil {
+			return fmt.Errorf("failed to restore task: %w", err)
+		}
+		if err := checkTrashed(res.RowsAffected()); err != nil {
+			return err
+		}
+
+		return recordChange(ctx, tx, id, AuditRestore, before)
+	})
+}
+
+// PurgeTask окончательно удаляет задачу, находящуюся в корзине
+func PurgeTask(ctx context.Context, id int64) error {
+	err := inTx(func(tx *sql.Tx) error {
+		return purgeTask(ctx, tx, id)
+	})
+	if err != nil {
+		return err
+	}
+	sweepAttachmentFiles()
+	return nil
+}
+
+func purgeTask(ctx context.Context, tx *sql.Tx, id int64) error {
+	before, err := snapshotTask(tx, id)
+	if err != nil {
+		return err
+	}
+
+	res, err := tx.Exec(`DELETE FROM scheduler WHERE id = ? AND deleted_at IS NOT NULL`, id)
+	if err != nil {
+		return fmt.Errorf("failed to purge task: %w", err)
+	}
+	if err := checkTrashed(res.RowsAffected()); err != nil {
+		return err
+	}
+
+	return recordChange(ctx, tx, id, AuditPurge, before)
+}
+
+// PurgeTrash окончательно удаляет задачи, попавшие в корзину раньше before
+func PurgeTrash(ctx context.Context, before time.Time) (int64, error) {
+	var purged int64
+	err := inTx(func(tx *sql.Tx) error {
+		rows, err := tx.Query(`SELECT id FROM scheduler WHERE deleted_at IS NOT NULL AND deleted_at < ?`,
+			formatTimestamp(before))
+		if err != nil {
+			return fmt.Errorf("failed to list expired trash: %w", err)
+		}
+
+		var ids []int64
+		for rows.Next() {
+			var id int64
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				return fmt.Errorf("row scan error: %w", err)
+			}
+			ids = append(ids, id)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("rows error: %w", err)
+		}
+
+		for _, id := range ids {
+			if err := purgeTask(ctx, tx, id); err != nil {
+				return err
+			}
+			purged++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	sweepAttachmentFiles()
+	return purged, nil
+}
+
+func checkTrashed(rowsAffected int64, err error) error {
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// startTrashPurge запускает фоновую очистку корзины от задач старше retention.
+// Возвращаемая функция останавливает очистку.
+func startTrashPurge(retention time.Duration) func() {
+	done := make(chan struct{})
+
+	purge := func() {
+		n, err := PurgeTrash(context.Background(), time.Now().Add(-retention))
+		if err != nil {
+			log.Printf("Trash purge error: %v", err)
+			return
+		}
+		if n > 0 {
+			log.Printf("Trash purge removed %d task(s)", n)
+		}
+	}
+
+	go func() {
+		ticker := time.NewTicker(trashPurgeInterval)
+		defer ticker.Stop()
+
+		purge()
+		for {
+			select {
+			case <-ticker.C:
+				purge()
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() { close(done) }
+}
@@ -0,0 +1,109 @@
+package tests
+
+import (
+	"bytes"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// attachDir возвращает каталог вложений сервера, как его выбирает pkg/db
+func attachDir() string {
+	if dir := os.Getenv("TODO_ATTACH_DIR"); dir != "" {
+		return dir
+	}
+	dbfile := DBFile
+	if envFile := os.Getenv("TODO_DBFILE"); envFile != "" {
+		dbfile = envFile
+	}
+	return filepath.Join(filepath.Dir(dbfile), "attachments")
+}
+
+// uploadAttachment загружает файл name с содержимым content к задаче taskID
+func uploadAttachment(t *testing.T, taskID int64, name string, content []byte) apiResponse {
+	var buf bytes.Buffer
+	form := multipart.NewWriter(&buf)
+	part, err := form.CreateFormFile("file", name)
+	assert.NoError(t, err)
+	_, err = part.Write(content)
+	assert.NoError(t, err)
+	assert.NoError(t, form.Close())
+
+	req, err := http.NewRequest(http.MethodPost, getURL(fmt.Sprintf("api/attachments?task_id=%d", taskID)), &buf)
+	assert.NoError(t, err)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	return apiDo(t, req)
+}
+
+func TestAttachments(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	id := newTask(t, map[string]any{"date": "20330901", "title": "Задача с вложением"})
+	countFiles := func() int {
+		files, err := os.ReadDir(attachDir())
+		assert.NoError(t, err)
+		return len(files)
+	}
+	filesBefore := countFiles()
+
+	content := []byte("Список покупок: хлеб, молоко")
+	ret := uploadAttachment(t, id, "../покупки.txt", content)
+	if !assert.Equal(t, http.StatusOK, ret.code, ret.body) {
+		return
+	}
+	attachID := int64(ret.body["id"].(float64))
+	assert.Equal(t, "покупки.txt", ret.body["name"], "путь из имени файла отбрасывается")
+	assert.Equal(t, "text/plain", ret.body["mime_type"])
+	assert.Equal(t, float64(len(content)), ret.body["size"])
+
+	path := fmt.Sprintf("api/attachments?id=%d", attachID)
+	ret = apiRequest(t, http.MethodGet, path, nil, nil)
+	assert.Equal(t, http.StatusOK, ret.code)
+	assert.Equal(t, content, ret.raw)
+	assert.Equal(t, "text/plain", ret.header.Get("Content-Type"))
+
+	ret = apiRequest(t, http.MethodGet, fmt.Sprintf("api/attachments?task_id=%d", id), nil, nil)
+	assert.Equal(t, http.StatusOK, ret.code, ret.body)
+	assert.Len(t, ret.body["attachments"], 1)
+
+	// Тип определяется по содержимому, а не по имени файла
+	ret = uploadAttachment(t, id, "картинка.png", []byte{0x7f, 'E', 'L', 'F', 0, 1, 2, 3})
+	assert.Equal(t, http.StatusUnsupportedMediaType, ret.code, ret.body)
+
+	// Лимит размера по умолчанию - 10 МиБ, файл ровно в лимит принимается
+	ret = uploadAttachment(t, id, "большой.txt", bytes.Repeat([]byte("a"), 10<<20+1))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, ret.code, ret.body)
+	ret = uploadAttachment(t, id, "в лимит.txt", bytes.Repeat([]byte("a"), 10<<20))
+	assert.Equal(t, http.StatusOK, ret.code, ret.body)
+
+	ret = uploadAttachment(t, 999999999, "нет задачи.txt", content)
+	assert.Equal(t, http.StatusNotFound, ret.code)
+
+	// Отклонённые загрузки не оставляют файлов на диске
+	var stored []string
+	err := db.Select(&stored, `SELECT stored_as FROM attachments WHERE task_id = ?`, id)
+	assert.NoError(t, err)
+	assert.Len(t, stored, 2)
+	assert.Equal(t, filesBefore+len(stored), countFiles())
+
+	// В корзине вложения сохраняются, при окончательном удалении задачи
+	// удаляются вместе с файлами
+	ret = apiRequest(t, http.MethodDelete, fmt.Sprintf("api/task?id=%d", id), nil, nil)
+	assert.Equal(t, http.StatusOK, ret.code, ret.body)
+	for _, name := range stored {
+		assert.FileExists(t, filepath.Join(attachDir(), name))
+	}
+	ret = apiRequest(t, http.MethodDelete, fmt.Sprintf("api/trash?id=%d", id), nil, nil)
+	assert.Equal(t, http.StatusOK, ret.code, ret.body)
+	for _, name := range stored {
+		assert.NoFileExists(t, filepath.Join(attachDir(), name))
+	}
+	ret = apiRequest(t, http.MethodGet, path, nil, nil)
+	assert.Equal(t, http.StatusNotFound, ret.code)
+}
@@ -0,0 +1,74 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// auditEntries возвращает журнал изменений задачи, начиная с самых свежих
+func auditEntries(t *testing.T, id int64) []map[string]any {
+	ret := apiRequest(t, http.MethodGet, fmt.Sprintf("api/audit?task_id=%d", id), nil, nil)
+	assert.Equal(t, http.StatusOK, ret.code)
+
+	var entries []map[string]any
+	for _, entry := range ret.body["entries"].([]any) {
+		entries = append(entries, entry.(map[string]any))
+	}
+	return entries
+}
+
+func TestAuditRevert(t *testing.T) {
+	id := newTask(t, map[string]any{
+		"date":    "20300125",
+		"title":   "Исходный заголовок",
+		"comment": "Исходный комментарий",
+	})
+	path := fmt.Sprintf("api/task?id=%d", id)
+
+	ret := apiRequest(t, http.MethodPut, "api/task", map[string]any{
+		"id":      id,
+		"date":    "20300126",
+		"title":   "Изменённый заголовок",
+		"comment": "",
+	}, nil)
+	assert.Equal(t, http.StatusOK, ret.code, ret.body)
+
+	entries := auditEntries(t, id)
+	if !assert.Len(t, entries, 2) {
+		return
+	}
+	update, insert := entries[0], entries[1]
+	assert.Equal(t, "update", update["action"])
+	assert.Equal(t, "insert", insert["action"])
+	assert.Nil(t, insert["before"])
+	assert.Equal(t, "Исходный заголовок", update["before"].(map[string]any)["title"])
+	assert.Equal(t, "Изменённый заголовок", update["after"].(map[string]any)["title"])
+
+	// Откат изменения возвращает прежние поля и сам попадает в журнал
+	ret = apiRequest(t, http.MethodPost, fmt.Sprintf("api/audit/revert?id=%.0f", update["id"]), nil, nil)
+	assert.Equal(t, http.StatusOK, ret.code, ret.body)
+
+	ret = apiRequest(t, http.MethodGet, path, nil, nil)
+	assert.Equal(t, http.StatusOK, ret.code)
+	assert.Equal(t, "Исходный заголовок", ret.body["title"])
+	assert.Equal(t, "Исходный комментарий", ret.body["comment"])
+	assert.Equal(t, "20300125", ret.body["date"])
+
+	entries = auditEntries(t, id)
+	if assert.Len(t, entries, 3) {
+		assert.Equal(t, "revert", entries[0]["action"])
+		assert.Equal(t, "Изменённый заголовок", entries[0]["before"].(map[string]any)["title"])
+	}
+
+	// Откат добавления перемещает задачу в корзину
+	ret = apiRequest(t, http.MethodPost, fmt.Sprintf("api/audit/revert?id=%.0f", insert["id"]), nil, nil)
+	assert.Equal(t, http.StatusOK, ret.code, ret.body)
+	ret = apiRequest(t, http.MethodGet, path, nil, nil)
+	assert.Equal(t, http.StatusNotFound, ret.code)
+
+	ret = apiRequest(t, http.MethodPost, "api/audit/revert?id=999999999", nil, nil)
+	assert.Equal(t, http.StatusNotFound, ret.code)
+}
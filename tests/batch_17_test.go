@@ -0,0 +1,99 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTasksBatch(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	first := newTask(t, map[string]any{
+		"date":  "20330301",
+		"due":   "20330310",
+		"title": "Пакет первая",
+		"tags":  []string{"testbatch"},
+	})
+	second := newTask(t, map[string]any{
+		"date":  "20330302",
+		"title": "Пакет вторая",
+		"tags":  []string{"testbatch"},
+	})
+
+	batch := func(req map[string]any) apiResponse {
+		return apiRequest(t, http.MethodPost, "api/tasks/batch", req, nil)
+	}
+	getTask := func(id int64) apiResponse {
+		return apiRequest(t, http.MethodGet, fmt.Sprintf("api/task?id=%d", id), nil, nil)
+	}
+	countTitle := func(title string) int {
+		var count int
+		err := db.Get(&count, `SELECT COUNT(*) FROM scheduler WHERE title = ?`, title)
+		assert.NoError(t, err)
+		return count
+	}
+
+	actions := []map[string]any{
+		{"action": "update", "task": map[string]any{"id": first, "date": "20330301", "title": "Пакет изменена"}},
+		{"action": "add", "task": map[string]any{"date": "20330303", "title": "Пакет добавлена"}},
+		{"action": "move_date", "id": second, "date": "20330305"},
+	}
+
+	// Ошибка одного действия откатывает весь пакет
+	failed := append(actions, map[string]any{"action": "delete", "id": 999999999})
+	ret := batch(map[string]any{"actions": failed})
+	assert.Equal(t, http.StatusConflict, ret.code, ret.body)
+	assert.Equal(t, false, ret.body["applied"])
+	results := ret.body["results"].([]any)
+	if assert.Len(t, results, 4) {
+		for i, result := range results[:3] {
+			assert.Empty(t, result.(map[string]any)["error"], i)
+		}
+		assert.NotEmpty(t, results[3].(map[string]any)["error"])
+	}
+	assert.Equal(t, "Пакет первая", getTask(first).body["title"])
+	assert.Equal(t, "20330302", getTask(second).body["date"])
+	assert.Zero(t, countTitle("Пакет добавлена"))
+
+	// Пробный прогон проверяет пакет, но ничего не меняет
+	ret = batch(map[string]any{"actions": actions, "dry_run": true})
+	assert.Equal(t, http.StatusOK, ret.code, ret.body)
+	assert.Equal(t, false, ret.body["applied"])
+	assert.Equal(t, true, ret.body["dry_run"])
+	assert.Len(t, ret.body["results"], 3)
+	assert.Equal(t, "Пакет первая", getTask(first).body["title"])
+	assert.Zero(t, countTitle("Пакет добавлена"))
+
+	ret = batch(map[string]any{
+		"search":  map[string]any{"tags": []string{"testbatch"}, "action": "done"},
+		"dry_run": true,
+	})
+	assert.Equal(t, http.StatusOK, ret.code, ret.body)
+	assert.Len(t, ret.body["results"], 2)
+	assert.Equal(t, "todo", getTask(first).body["status"])
+
+	// Успешный пакет применяется целиком
+	ret = batch(map[string]any{"actions": actions})
+	assert.Equal(t, http.StatusOK, ret.code, ret.body)
+	assert.Equal(t, true, ret.body["applied"])
+	results = ret.body["results"].([]any)
+	if assert.Len(t, results, 3) {
+		added := int64(results[1].(map[string]any)["id"].(float64))
+		deleteAfterTest(t, added)
+		assert.Equal(t, "Пакет добавлена", getTask(added).body["title"])
+	}
+
+	task := getTask(first)
+	assert.Equal(t, "Пакет изменена", task.body["title"])
+	assert.Equal(t, "20330310", task.body["due"], "срок без поля due не меняется")
+	assert.Equal(t, "20330305", getTask(second).body["date"])
+
+	ret = batch(map[string]any{"actions": []map[string]any{{"action": "unknown", "id": first}}})
+	assert.Equal(t, http.StatusConflict, ret.code)
+	ret = batch(map[string]any{"actions": []map[string]any{}})
+	assert.Equal(t, http.StatusBadRequest, ret.code)
+}
@@ -0,0 +1,45 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// BenchmarkTasksParallel нагружает /api/tasks параллельными чтениями,
+// одновременно добавляя задачи, чтобы проверить отсутствие `database is locked`.
+func BenchmarkTasksParallel(b *testing.B) {
+	today := time.Now().Format(`20060102`)
+	// Заголовки уникальны для всех горутин, иначе задача отклоняется как дубликат
+	var added atomic.Int64
+
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			i++
+			if i%10 == 0 {
+				ret, err := postJSON("api/addtask", map[string]any{
+					"date":  today,
+					"title": fmt.Sprintf("Нагрузка %d", added.Add(1)),
+				}, http.MethodPost)
+				if err != nil {
+					b.Fatal(err)
+				}
+				if e, ok := ret["error"]; ok {
+					b.Fatalf("add task: %v", e)
+				}
+				continue
+			}
+
+			body, err := requestJSON("api/tasks", nil, http.MethodGet)
+			if err != nil {
+				b.Fatal(err)
+			}
+			if len(body) == 0 {
+				b.Fatal("empty response")
+			}
+		}
+	})
+}
@@ -0,0 +1,61 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// checklist возвращает отметки пунктов чек-листа задачи по порядку
+func checklist(t *testing.T, id int64) []bool {
+	ret := apiRequest(t, http.MethodGet, fmt.Sprintf("api/checklist?task_id=%d", id), nil, nil)
+	assert.Equal(t, http.StatusOK, ret.code, ret.body)
+
+	var done []bool
+	for _, item := range ret.body["items"].([]any) {
+		done = append(done, item.(map[string]any)["done"].(bool))
+	}
+	return done
+}
+
+// fillChecklist добавляет пункты в чек-лист задачи и отмечает их выполненными
+func fillChecklist(t *testing.T, id int64, titles ...string) {
+	for _, title := range titles {
+		ret := apiRequest(t, http.MethodPost, "api/checklist", map[string]any{"task_id": id, "title": title}, nil)
+		assert.Equal(t, http.StatusOK, ret.code, ret.body)
+		ret = apiRequest(t, http.MethodPut, "api/checklist",
+			map[string]any{"id": ret.body["id"], "title": title, "done": true}, nil)
+		assert.Equal(t, http.StatusOK, ret.code, ret.body)
+	}
+}
+
+func TestChecklistReset(t *testing.T) {
+	weekly := newTask(t, map[string]any{
+		"date":   "20330801",
+		"title":  "Уборка",
+		"repeat": "d 7",
+	})
+	fillChecklist(t, weekly, "Пропылесосить", "Помыть полы")
+	assert.Equal(t, []bool{true, true}, checklist(t, weekly))
+
+	path := fmt.Sprintf("api/task?id=%d", weekly)
+	ret := apiRequest(t, http.MethodGet, path, nil, nil)
+	assert.Equal(t, map[string]any{"done": float64(2), "total": float64(2)}, ret.body["checklist"])
+
+	// Следующее повторение начинается с невыполненным чек-листом
+	ret = apiRequest(t, http.MethodPost, fmt.Sprintf("api/task/done?id=%d", weekly), nil, nil)
+	assert.Equal(t, http.StatusOK, ret.code, ret.body)
+	assert.Equal(t, []bool{false, false}, checklist(t, weekly))
+	ret = apiRequest(t, http.MethodGet, path, nil, nil)
+	assert.Equal(t, "20330808", ret.body["date"])
+	assert.Equal(t, map[string]any{"done": float64(0), "total": float64(2)}, ret.body["checklist"])
+
+	// У разовой задачи отметки остаются после выполнения
+	once := newTask(t, map[string]any{"date": "20330801", "title": "Переезд"})
+	fillChecklist(t, once, "Упаковать книги")
+	ret = apiRequest(t, http.MethodPost, fmt.Sprintf("api/task/done?id=%d", once), nil, nil)
+	assert.Equal(t, http.StatusOK, ret.code, ret.body)
+	assert.Equal(t, []bool{true}, checklist(t, once))
+}
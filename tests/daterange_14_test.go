@@ -0,0 +1,70 @@
+package tests
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDateRangeFilter(t *testing.T) {
+	const tag = "tag:testdaterange "
+	add := func(date, due string) int64 {
+		return newTask(t, map[string]any{
+			"date":  date,
+			"due":   due,
+			"title": "Диапазон дат",
+			"tags":  []string{"testdaterange"},
+		})
+	}
+
+	now := time.Now()
+	first := add("20320301", "20320305")
+	second := add("20320310", "")
+	third := add("20320320", "20320315")
+	today := add(now.Format(`20060102`), "")
+	tomorrow := add(now.AddDate(0, 0, 1).Format(`20060102`), "")
+
+	tests := []struct {
+		search string
+		want   []int64
+	}{
+		{"on:01.03.2032-10.03.2032", []int64{first, second}},
+		{"on:2032-03-01..2032-03-10", []int64{first, second}},
+		{"on:20320301-20320310", []int64{first, second}},
+		{"on:10.03.2032", []int64{second}},
+		{"after:31.12.2031 before:10.03.2032", []int64{first}},
+		{"after:10.03.2032", []int64{third}},
+		{"after:01.03.2032-10.03.2032", []int64{third}},
+		{"due:yes", []int64{first, third}},
+		{"due:no", []int64{second, today, tomorrow}},
+		{"duebefore:10.03.2032", []int64{first}},
+		{"dueafter:05.03.2032", []int64{third}},
+		{"due:01.03.2032-31.03.2032", []int64{first, third}},
+		{"on:today", []int64{today}},
+		{"on:сегодня", []int64{today}},
+		{"on:tomorrow", []int64{tomorrow}},
+		{"on:завтра OR on:today", []int64{today, tomorrow}},
+	}
+	for _, v := range tests {
+		assert.ElementsMatch(t, v.want, searchTasks(t, tag+v.search), v.search)
+	}
+	// Границы недели и месяца зависят от текущей даты, но сегодняшний день в них входит
+	for _, search := range []string{`on:"this week"`, `on:"в этом месяце"`} {
+		found := searchTasks(t, tag+search)
+		assert.Contains(t, found, today, search)
+		assert.NotContains(t, found, first, search)
+	}
+
+	for _, search := range []string{
+		"on:10.03.2032-01.03.2032",
+		"on:32.13.2032",
+		"before:когда-нибудь",
+		"due:01.03.2032..",
+	} {
+		ret := apiRequest(t, http.MethodGet, "api/tasks?"+url.Values{"search": {tag + search}}.Encode(), nil, nil)
+		assert.Equal(t, http.StatusBadRequest, ret.code, search)
+	}
+}
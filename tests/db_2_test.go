@@ -1,6 +1,7 @@
 package tests
 
 import (
+	"database/sql"
 	"os"
 	"testing"
 	"time"
@@ -11,11 +12,19 @@ import (
 )
 
 type Task struct {
-	ID      int64  `db:"id"`
-	Date    string `db:"date"`
-	Title   string `db:"title"`
-	Comment string `db:"comment"`
-	Repeat  string `db:"repeat"`
+	ID          int64          `db:"id"`
+	Date        string         `db:"date"`
+	Due         string         `db:"due"`
+	Title       string         `db:"title"`
+	Comment     string         `db:"comment"`
+	Repeat      string         `db:"repeat"`
+	Version     int64          `db:"version"`
+	ProjectID   int64          `db:"project_id"`
+	Priority    int            `db:"priority"`
+	Rank        string         `db:"rank"`
+	Status      string         `db:"status"`
+	CompletedAt sql.NullString `db:"completed_at"`
+	DeletedAt   sql.NullString `db:"deleted_at"`
 }
 
 func count(db *sqlx.DB) (int, error) {
@@ -0,0 +1,93 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDependencyCycle(t *testing.T) {
+	var tasks []int64
+	for _, title := range []string{"Купить краску", "Покрасить стену", "Повесить картину"} {
+		tasks = append(tasks, newTask(t, map[string]any{"date": "20330201", "title": title}))
+	}
+	first, second, third := tasks[0], tasks[1], tasks[2]
+
+	depend := func(id, blocker int64) int {
+		ret := apiRequest(t, http.MethodPost, "api/task/dependencies",
+			map[string]any{"task_id": id, "blocker_id": blocker}, nil)
+		return ret.code
+	}
+
+	// Цепочка: вторая ждёт первую, третья - вторую
+	assert.Equal(t, http.StatusOK, depend(second, first))
+	assert.Equal(t, http.StatusOK, depend(third, second))
+
+	ret := apiRequest(t, http.MethodGet, fmt.Sprintf("api/task?id=%d", second), nil, nil)
+	assert.Equal(t, []any{float64(first)}, ret.body["blocked_by"])
+	assert.Equal(t, []any{float64(third)}, ret.body["blocks"])
+	assert.Equal(t, true, ret.body["blocked"])
+
+	// Прямой, транзитивный цикл и зависимость от самой себя отклоняются
+	assert.Equal(t, http.StatusConflict, depend(first, second))
+	assert.Equal(t, http.StatusConflict, depend(first, third))
+	assert.Equal(t, http.StatusConflict, depend(first, first))
+
+	ret = apiRequest(t, http.MethodGet, fmt.Sprintf("api/task?id=%d", first), nil, nil)
+	assert.Nil(t, ret.body["blocked_by"])
+
+	// Повтор существующей зависимости не ошибка
+	assert.Equal(t, http.StatusOK, depend(second, first))
+	assert.Equal(t, http.StatusNotFound, depend(999999999, first))
+
+	// Без звена цепочки цикла больше нет
+	ret = apiRequest(t, http.MethodDelete,
+		fmt.Sprintf("api/task/dependencies?task_id=%d&blocker_id=%d", third, second), nil, nil)
+	assert.Equal(t, http.StatusOK, ret.code)
+	assert.Equal(t, http.StatusOK, depend(first, third))
+}
+
+func TestDependencyVersion(t *testing.T) {
+	blocker := newTask(t, map[string]any{"date": "20330202", "title": "Согласовать макет"})
+	task := newTask(t, map[string]any{"date": "20330202", "title": "Сверстать страницу"})
+
+	etags := func() []string {
+		var tags []string
+		for _, id := range []int64{task, blocker} {
+			ret := apiRequest(t, http.MethodGet, fmt.Sprintf("api/task?id=%d", id), nil, nil)
+			tags = append(tags, ret.header.Get("ETag"))
+		}
+		return tags
+	}
+
+	// Зависимость меняет blocked_by и blocks, поэтому версии обеих задач
+	// растут, а изменение попадает в журнал
+	before := etags()
+	ret := apiRequest(t, http.MethodPost, "api/task/dependencies",
+		map[string]any{"task_id": task, "blocker_id": blocker}, nil)
+	assert.Equal(t, http.StatusOK, ret.code)
+	added := etags()
+	for i := range before {
+		assert.NotEqual(t, before[i], added[i])
+	}
+	assert.Equal(t, "update", auditEntries(t, task)[0]["action"])
+	assert.Equal(t, "update", auditEntries(t, blocker)[0]["action"])
+
+	// Повтор ничего не меняет
+	ret = apiRequest(t, http.MethodPost, "api/task/dependencies",
+		map[string]any{"task_id": task, "blocker_id": blocker}, nil)
+	assert.Equal(t, http.StatusOK, ret.code)
+	assert.Equal(t, added, etags())
+
+	entries := len(auditEntries(t, task))
+	ret = apiRequest(t, http.MethodDelete,
+		fmt.Sprintf("api/task/dependencies?task_id=%d&blocker_id=%d", task, blocker), nil, nil)
+	assert.Equal(t, http.StatusOK, ret.code)
+	deleted := etags()
+	for i := range added {
+		assert.NotEqual(t, added[i], deleted[i])
+	}
+	assert.Len(t, auditEntries(t, task), entries+1)
+}
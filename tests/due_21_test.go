@@ -0,0 +1,54 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDueDate(t *testing.T) {
+	// При выполнении периодической задачи срок сдвигается вместе с датой
+	id := newTask(t, map[string]any{
+		"date":   "20330701",
+		"due":    "20330705",
+		"title":  "Сдать показания",
+		"repeat": "d 7",
+	})
+	ret := apiRequest(t, http.MethodPost, fmt.Sprintf("api/task/done?id=%d&date=20330701", id), nil, nil)
+	assert.Equal(t, http.StatusOK, ret.code, ret.body)
+	ret = apiRequest(t, http.MethodGet, fmt.Sprintf("api/task?id=%d", id), nil, nil)
+	assert.Equal(t, "20330708", ret.body["date"])
+	assert.Equal(t, "20330712", ret.body["due"])
+
+	// Просроченными считаются только открытые задачи, и в поиске, и в поле overdue
+	now := time.Now()
+	add := func(title string) int64 {
+		return newTask(t, map[string]any{
+			"date":  now.Format(`20060102`),
+			"due":   now.AddDate(0, 0, -1).Format(`20060102`),
+			"title": title,
+			"tags":  []string{"testdue"},
+		})
+	}
+	open := add("Просроченная открытая")
+	done := add("Просроченная выполненная")
+	ret = apiRequest(t, http.MethodPost, fmt.Sprintf("api/task/done?id=%d", done), nil, nil)
+	assert.Equal(t, http.StatusOK, ret.code, ret.body)
+
+	query := url.Values{"search": {"tag:testdue overdue"}, "status": {"all"}}
+	ret = apiRequest(t, http.MethodGet, "api/tasks?"+query.Encode(), nil, nil)
+	assert.Equal(t, http.StatusOK, ret.code, ret.body)
+	assert.Equal(t, []int64{open}, ids(ret))
+
+	query.Set("search", "tag:testdue")
+	ret = apiRequest(t, http.MethodGet, "api/tasks?"+query.Encode(), nil, nil)
+	for _, task := range ret.body["tasks"].([]any) {
+		task := task.(map[string]any)
+		assert.Equal(t, task["id"] == float64(open), task["overdue"] == true, task["title"])
+	}
+	assert.ElementsMatch(t, []int64{open, done}, ids(ret))
+}
@@ -0,0 +1,53 @@
+package tests
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDuplicateTasks(t *testing.T) {
+	original := newTask(t, map[string]any{"date": "20330501", "title": "Позвонить маме"})
+
+	add := func(apipath, date, title string) apiResponse {
+		ret := apiRequest(t, http.MethodPost, apipath, map[string]any{"date": date, "title": title}, nil)
+		if id, ok := ret.body["id"].(float64); ok {
+			deleteAfterTest(t, int64(id))
+		}
+		return ret
+	}
+
+	// Совпадение после нормализации названия не мешает добавлению,
+	// но возвращается как предупреждение
+	ret := add("api/addtask", "20330501", "  позвонить   МАМЕ ")
+	assert.Equal(t, http.StatusOK, ret.code, ret.body)
+	assert.NotNil(t, ret.body["id"])
+	assert.Equal(t, []any{float64(original)}, ret.body["duplicates"])
+
+	// Похожая, но не совпадающая задача тоже добавляется с предупреждением,
+	// самые похожие задачи идут первыми
+	ret = add("api/addtask", "20330501", "Позвонить маме!")
+	assert.Equal(t, http.StatusOK, ret.code, ret.body)
+	assert.NotNil(t, ret.body["id"])
+	ret = add("api/addtask", "20330501", "Позвонить папе")
+	assert.Equal(t, http.StatusOK, ret.code, ret.body)
+	assert.NotNil(t, ret.body["id"])
+	if assert.NotEmpty(t, ret.body["duplicates"]) {
+		assert.Contains(t, ret.body["duplicates"], float64(original))
+	}
+
+	// force=true отключает проверку
+	ret = add("api/addtask?force=true", "20330501", "Позвонить маме")
+	assert.Equal(t, http.StatusOK, ret.code, ret.body)
+	assert.NotNil(t, ret.body["id"])
+	assert.Nil(t, ret.body["duplicates"])
+
+	// В другой день это уже не дубликат, как и непохожая задача в тот же день
+	ret = add("api/addtask", "20330502", "Позвонить маме")
+	assert.Equal(t, http.StatusOK, ret.code, ret.body)
+	assert.Nil(t, ret.body["duplicates"])
+	ret = add("api/addtask", "20330501", "Купить хлеб")
+	assert.Equal(t, http.StatusOK, ret.code, ret.body)
+	assert.Nil(t, ret.body["duplicates"])
+}
@@ -0,0 +1,56 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTaskETag(t *testing.T) {
+	id := newTask(t, map[string]any{
+		"date":  "20300115",
+		"title": "Проверка версии",
+	})
+	path := fmt.Sprintf("api/task?id=%d", id)
+
+	ret := apiRequest(t, http.MethodGet, path, nil, nil)
+	assert.Equal(t, http.StatusOK, ret.code)
+	etag := ret.header.Get("ETag")
+	assert.NotEmpty(t, etag)
+
+	update := map[string]any{"id": id, "date": "20300115", "title": "Новый заголовок"}
+	ret = apiRequest(t, http.MethodPut, "api/task", update, map[string]string{"If-Match": etag})
+	assert.Equal(t, http.StatusOK, ret.code, ret.body)
+	newETag := ret.header.Get("ETag")
+	assert.NotEmpty(t, newETag)
+	assert.NotEqual(t, etag, newETag, "ETag должен меняться при изменении задачи")
+
+	ret = apiRequest(t, http.MethodGet, path, nil, nil)
+	assert.Equal(t, newETag, ret.header.Get("ETag"))
+
+	// Устаревшая версия
+	update["title"] = "Потерянное изменение"
+	ret = apiRequest(t, http.MethodPut, "api/task", update, map[string]string{"If-Match": etag})
+	assert.Equal(t, http.StatusPreconditionFailed, ret.code)
+	ret = apiRequest(t, http.MethodDelete, path, nil, map[string]string{"If-Match": etag})
+	assert.Equal(t, http.StatusPreconditionFailed, ret.code)
+
+	// If-Match сравнивает строго, слабый валидатор не совпадает даже с текущей версией
+	ret = apiRequest(t, http.MethodPut, "api/task", update, map[string]string{"If-Match": "W/" + newETag})
+	assert.Equal(t, http.StatusPreconditionFailed, ret.code)
+
+	ret = apiRequest(t, http.MethodGet, path, nil, nil)
+	assert.Equal(t, http.StatusOK, ret.code)
+	assert.Equal(t, "Новый заголовок", ret.body["title"])
+
+	// Некорректный заголовок - ошибка запроса, а не несовпадение версии
+	for _, value := range []string{"abc", `"-1"`, `"0"`} {
+		ret = apiRequest(t, http.MethodPut, "api/task", update, map[string]string{"If-Match": value})
+		assert.Equal(t, http.StatusBadRequest, ret.code, value)
+	}
+
+	ret = apiRequest(t, http.MethodDelete, path, nil, map[string]string{"If-Match": newETag})
+	assert.Equal(t, http.StatusOK, ret.code, ret.body)
+}
@@ -0,0 +1,80 @@
+package tests
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// searchTasks возвращает ID задач, найденных строкой поиска
+func searchTasks(t *testing.T, search string) []int64 {
+	ret := apiRequest(t, http.MethodGet, "api/tasks?"+url.Values{"search": {search}}.Encode(), nil, nil)
+	assert.Equal(t, http.StatusOK, ret.code, search, ret.body)
+	return ids(ret)
+}
+
+func TestFilterQuery(t *testing.T) {
+	first := newTask(t, map[string]any{
+		"date":  "20310301",
+		"title": "Альфа фильтра",
+		"tags":  []string{"testfiltera"},
+	})
+	second := newTask(t, map[string]any{
+		"date":   "20310302",
+		"title":  "Бета фильтра",
+		"repeat": "d 3",
+		"tags":   []string{"testfilterb"},
+	})
+	third := newTask(t, map[string]any{
+		"date":  "20310303",
+		"title": "Гамма фильтра",
+		"tags":  []string{"testfiltera", "testfilterb"},
+	})
+
+	tests := []struct {
+		search string
+		want   []int64
+	}{
+		{"tag:testfiltera", []int64{first, third}},
+		{"tag:testfiltera tag:testfilterb", []int64{third}},
+		{"tag:testfiltera AND tag:testfilterb", []int64{third}},
+		{"tag:testfiltera -tag:testfilterb", []int64{first}},
+		{"tag:testfiltera OR tag:testfilterb", []int64{first, second, third}},
+		// AND связывает сильнее OR
+		{"tag:testfilterb OR tag:testfiltera repeat:yes", []int64{second, third}},
+		{"(tag:testfilterb OR tag:testfiltera) repeat:yes", []int64{second}},
+		{"tag:testfiltera OR tag:testfilterb -repeat:yes", []int64{first, third}},
+		{"-(tag:testfiltera OR repeat:yes) tag:testfilterb", []int64{}},
+		{"tag:testfilterb repeat:d", []int64{second}},
+		{"tag:testfiltera гамма", []int64{third}},
+		{`tag:testfiltera OR "бета фильтра"`, []int64{first, second, third}},
+	}
+	for _, v := range tests {
+		found := searchTasks(t, v.search)
+		if len(v.want) == 0 {
+			assert.Empty(t, found, v.search)
+			continue
+		}
+		assert.ElementsMatch(t, v.want, found, v.search)
+	}
+
+	for _, search := range []string{
+		"(tag:testfiltera",
+		"tag:testfiltera)",
+		"()",
+		"tag:",
+		"OR tag:testfiltera",
+		"tag:testfiltera OR",
+		"-",
+		`"незакрытая фраза`,
+		"before:завтрашний",
+		"repeat:maybe",
+		"blocked:maybe",
+	} {
+		ret := apiRequest(t, http.MethodGet, "api/tasks?"+url.Values{"search": {search}}.Encode(), nil, nil)
+		assert.Equal(t, http.StatusBadRequest, ret.code, search)
+		assert.NotEmpty(t, ret.body["error"], search)
+	}
+}
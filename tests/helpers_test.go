@@ -0,0 +1,96 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// apiResponse - ответ API с кодом и заголовками. Тело JSON разбирается
+// в body, raw содержит тело как есть.
+type apiResponse struct {
+	code   int
+	header http.Header
+	body   map[string]any
+	raw    []byte
+}
+
+// apiRequest выполняет запрос к API с заголовками header и разбирает ответ
+func apiRequest(t *testing.T, method, apipath string, values any, header map[string]string) apiResponse {
+	var data []byte
+	if values != nil {
+		var err error
+		data, err = json.Marshal(values)
+		assert.NoError(t, err)
+	}
+
+	req, err := http.NewRequest(method, getURL(apipath), bytes.NewBuffer(data))
+	assert.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	return apiDo(t, req)
+}
+
+// apiDo отправляет подготовленный запрос к API с токеном из настроек
+func apiDo(t *testing.T, req *http.Request) apiResponse {
+	client := &http.Client{}
+	if len(Token) > 0 {
+		jar, err := cookiejar.New(nil)
+		assert.NoError(t, err)
+		jar.SetCookies(req.URL, []*http.Cookie{{Name: "token", Value: Token}})
+		client.Jar = jar
+	}
+
+	resp, err := client.Do(req)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+
+	ret := apiResponse{code: resp.StatusCode, header: resp.Header, raw: body}
+	if len(body) > 0 && strings.HasPrefix(resp.Header.Get("Content-Type"), "application/json") {
+		assert.NoError(t, json.Unmarshal(body, &ret.body), string(body))
+	}
+	return ret
+}
+
+// newTask добавляет задачу, которая удаляется после теста
+func newTask(t *testing.T, values map[string]any) int64 {
+	ret := apiRequest(t, http.MethodPost, "api/addtask", values, nil)
+	if !assert.Equal(t, http.StatusOK, ret.code, ret.body) {
+		t.FailNow()
+	}
+	id := int64(ret.body["id"].(float64))
+	deleteAfterTest(t, id)
+	return id
+}
+
+// deleteAfterTest окончательно удаляет задачу после теста
+func deleteAfterTest(t *testing.T, id int64) {
+	t.Cleanup(func() {
+		apiRequest(t, http.MethodDelete, fmt.Sprintf("api/task?id=%d", id), nil, nil)
+		apiRequest(t, http.MethodDelete, fmt.Sprintf("api/trash?id=%d", id), nil, nil)
+	})
+}
+
+// ids возвращает ID задач из списка tasks ответа
+func ids(ret apiResponse) []int64 {
+	var list []int64
+	tasks, _ := ret.body["tasks"].([]any)
+	for _, task := range tasks {
+		list = append(list, int64(task.(map[string]any)["id"].(float64)))
+	}
+	return list
+}
@@ -0,0 +1,81 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// completions возвращает записи истории из ответа, оставляя только записи задачи id
+func completions(t *testing.T, path string, id int64) []map[string]any {
+	ret := apiRequest(t, http.MethodGet, path, nil, nil)
+	assert.Equal(t, http.StatusOK, ret.code, ret.body)
+
+	var list []map[string]any
+	for _, item := range ret.body["completions"].([]any) {
+		entry := item.(map[string]any)
+		if entry["task_id"] == float64(id) {
+			list = append(list, entry)
+		}
+	}
+	return list
+}
+
+func TestHistory(t *testing.T) {
+	id := newTask(t, map[string]any{
+		"date":   "20330301",
+		"title":  "Проверить почту",
+		"repeat": "d 1",
+	})
+
+	for _, note := range []string{"Первый раз", "Второй раз"} {
+		ret := apiRequest(t, http.MethodPost, fmt.Sprintf("api/task/done?id=%d", id),
+			map[string]any{"note": note}, nil)
+		assert.Equal(t, http.StatusOK, ret.code, ret.body)
+	}
+
+	// История задачи: свежие записи первыми, с заметкой и плановой датой
+	list := completions(t, fmt.Sprintf("api/task/history?id=%d", id), id)
+	if assert.Len(t, list, 2) {
+		assert.Equal(t, "Второй раз", list[0]["note"])
+		assert.Equal(t, "20330302", list[0]["scheduled_date"])
+		assert.Equal(t, "Первый раз", list[1]["note"])
+		assert.Equal(t, "20330301", list[1]["scheduled_date"])
+		assert.Equal(t, "Проверить почту", list[1]["title"])
+	}
+	assert.Len(t, completions(t, fmt.Sprintf("api/task/history?id=%d&limit=1", id), id), 1)
+
+	// Общая лента с диапазоном дат выполнения, границы включительные
+	now := time.Now()
+	today := now.Format(`20060102`)
+	yesterday := now.AddDate(0, 0, -1).Format(`20060102`)
+	tomorrow := now.AddDate(0, 0, 1).Format(`20060102`)
+
+	tests := []struct {
+		query string
+		count int
+	}{
+		{"", 2},
+		{"from=" + today, 2},
+		{"to=" + today, 2},
+		{"from=" + yesterday + "&to=" + tomorrow, 2},
+		{"from=" + tomorrow, 0},
+		{"to=" + yesterday, 0},
+	}
+	for _, v := range tests {
+		path := "api/history?limit=500&" + v.query
+		assert.Len(t, completions(t, path, id), v.count, v.query)
+		path = fmt.Sprintf("api/task/history?id=%d&%s", id, v.query)
+		assert.Len(t, completions(t, path, id), v.count, v.query)
+	}
+
+	for _, query := range []string{"from=01.03.2033", "to=2033-03-01", "limit=0", "limit=x"} {
+		ret := apiRequest(t, http.MethodGet, "api/history?"+query, nil, nil)
+		assert.Equal(t, http.StatusBadRequest, ret.code, query)
+	}
+	ret := apiRequest(t, http.MethodGet, "api/task/history", nil, nil)
+	assert.Equal(t, http.StatusBadRequest, ret.code)
+}
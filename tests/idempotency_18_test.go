@@ -0,0 +1,76 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIdempotencyKey(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	key := fmt.Sprintf("test-%d", time.Now().UnixNano())
+	title := "Идемпотентная " + key
+	header := map[string]string{"Idempotency-Key": key}
+	similar := newTask(t, map[string]any{"date": "20330401", "title": title + "!"})
+
+	ret := apiRequest(t, http.MethodPost, "api/addtask", map[string]any{
+		"date":  "20330401",
+		"title": title,
+	}, header)
+	if !assert.Equal(t, http.StatusOK, ret.code, ret.body) {
+		return
+	}
+	id := ret.body["id"]
+	deleteAfterTest(t, int64(id.(float64)))
+	assert.Empty(t, ret.header.Get("Idempotent-Replayed"))
+	assert.Equal(t, []any{float64(similar)}, ret.body["duplicates"])
+
+	// Повтор отдаёт сохранённый ответ, а не собирает новый
+	ret = apiRequest(t, http.MethodDelete, fmt.Sprintf("api/task?id=%d", similar), nil, nil)
+	assert.Equal(t, http.StatusOK, ret.code, ret.body)
+
+	// Повтор с тем же ключом - тот же ответ без новой задачи, даже если
+	// JSON записан иначе
+	body := json.RawMessage(fmt.Sprintf(`{"title": %q, "date": "20330401"}`, title))
+	ret = apiRequest(t, http.MethodPost, "api/addtask", body, header)
+	assert.Equal(t, http.StatusOK, ret.code, ret.body)
+	assert.Equal(t, id, ret.body["id"])
+	assert.Equal(t, []any{float64(similar)}, ret.body["duplicates"])
+	assert.Equal(t, "true", ret.header.Get("Idempotent-Replayed"))
+
+	var count int
+	err := db.Get(&count, `SELECT COUNT(*) FROM scheduler WHERE title = ?`, title)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	// Тот же ключ с другим запросом
+	ret = apiRequest(t, http.MethodPost, "api/addtask", map[string]any{
+		"date":  "20330402",
+		"title": title,
+	}, header)
+	assert.Equal(t, http.StatusUnprocessableEntity, ret.code)
+	assert.NotEmpty(t, ret.body["error"])
+
+	ret = apiRequest(t, http.MethodPost, "api/addtask?force=true", map[string]any{
+		"date":  "20330401",
+		"title": title,
+	}, header)
+	assert.Equal(t, http.StatusUnprocessableEntity, ret.code, "параметры запроса входят в сравнение")
+
+	ret = apiRequest(t, http.MethodPost, "api/addtask", map[string]any{
+		"date":  "20330401",
+		"title": title,
+	}, map[string]string{"Idempotency-Key": strings.Repeat("k", 256)})
+	assert.Equal(t, http.StatusBadRequest, ret.code)
+
+	err = db.Get(&count, `SELECT COUNT(*) FROM scheduler WHERE title = ?`, title)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+}
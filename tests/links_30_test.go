@@ -0,0 +1,59 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// linkIDs возвращает ID задач из поля links или backlinks задачи id
+func linkIDs(t *testing.T, id int64, field string) []int64 {
+	ret := apiRequest(t, http.MethodGet, fmt.Sprintf("api/task?id=%d", id), nil, nil)
+	assert.Equal(t, http.StatusOK, ret.code, ret.body)
+
+	// Пустые списки не попадают в ответ
+	links, _ := ret.body[field].([]any)
+	list := []int64{}
+	for _, link := range links {
+		list = append(list, int64(link.(map[string]any)["id"].(float64)))
+	}
+	return list
+}
+
+func TestTaskLinks(t *testing.T) {
+	target := newTask(t, map[string]any{"date": "20330901", "title": "Заказать билеты"})
+	other := newTask(t, map[string]any{"date": "20330901", "title": "Забронировать отель"})
+	source := newTask(t, map[string]any{
+		"date":  "20330901",
+		"title": "Спланировать отпуск",
+		// Повтор, ссылка на себя, несуществующая задача и HTML-сущность не считаются
+		"comment": fmt.Sprintf("Сначала #%d, потом #%d и снова #%d; #999999999 &#%d;",
+			target, other, target, other),
+	})
+
+	assert.Equal(t, []int64{target, other}, linkIDs(t, source, "links"))
+	assert.Equal(t, []int64{source}, linkIDs(t, target, "backlinks"))
+	assert.Equal(t, []int64{}, linkIDs(t, source, "backlinks"))
+
+	// Задача в корзине пропадает из ссылок и возвращается после восстановления
+	ret := apiRequest(t, http.MethodDelete, fmt.Sprintf("api/task?id=%d", target), nil, nil)
+	assert.Equal(t, http.StatusOK, ret.code)
+	assert.Equal(t, []int64{other}, linkIDs(t, source, "links"))
+
+	ret = apiRequest(t, http.MethodPost, fmt.Sprintf("api/trash/restore?id=%d", target), nil, nil)
+	assert.Equal(t, http.StatusOK, ret.code, ret.body)
+	assert.Equal(t, []int64{target, other}, linkIDs(t, source, "links"))
+	assert.Equal(t, []int64{source}, linkIDs(t, target, "backlinks"))
+
+	// Удалённая окончательно задача исчезает из ссылок навсегда
+	apiRequest(t, http.MethodDelete, fmt.Sprintf("api/task?id=%d", other), nil, nil)
+	ret = apiRequest(t, http.MethodDelete, fmt.Sprintf("api/trash?id=%d", other), nil, nil)
+	assert.Equal(t, http.StatusOK, ret.code, ret.body)
+	assert.Equal(t, []int64{target}, linkIDs(t, source, "links"))
+
+	// Удаление задачи-источника убирает обратную ссылку
+	apiRequest(t, http.MethodDelete, fmt.Sprintf("api/task?id=%d", source), nil, nil)
+	assert.Equal(t, []int64{}, linkIDs(t, target, "backlinks"))
+}
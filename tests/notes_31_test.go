@@ -0,0 +1,70 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// notes возвращает заметки задачи по порядку добавления
+func notes(t *testing.T, id int64) []map[string]any {
+	ret := apiRequest(t, http.MethodGet, fmt.Sprintf("api/notes?task_id=%d", id), nil, nil)
+	assert.Equal(t, http.StatusOK, ret.code, ret.body)
+
+	var list []map[string]any
+	for _, note := range ret.body["notes"].([]any) {
+		list = append(list, note.(map[string]any))
+	}
+	return list
+}
+
+func TestNotes(t *testing.T) {
+	id := newTask(t, map[string]any{"date": "20331001", "title": "Собрать документы"})
+
+	var noteIDs []any
+	for _, text := range []string{"  Нужна справка  ", "Копия паспорта"} {
+		ret := apiRequest(t, http.MethodPost, "api/notes", map[string]any{"task_id": id, "text": text}, nil)
+		assert.Equal(t, http.StatusOK, ret.code, ret.body)
+		noteIDs = append(noteIDs, ret.body["id"])
+	}
+
+	list := notes(t, id)
+	if assert.Len(t, list, 2) {
+		assert.Equal(t, "Нужна справка", list[0]["text"])
+		assert.NotEmpty(t, list[0]["author"])
+		assert.NotEmpty(t, list[0]["created_at"])
+		assert.Nil(t, list[0]["edited_at"])
+	}
+
+	// Правка меняет текст и отмечает время правки
+	ret := apiRequest(t, http.MethodPut, "api/notes", map[string]any{"id": noteIDs[0], "text": "Нужны две справки"}, nil)
+	assert.Equal(t, http.StatusOK, ret.code, ret.body)
+	list = notes(t, id)
+	if assert.Len(t, list, 2) {
+		assert.Equal(t, "Нужны две справки", list[0]["text"])
+		assert.NotEmpty(t, list[0]["edited_at"])
+		assert.Equal(t, "Копия паспорта", list[1]["text"])
+	}
+
+	ret = apiRequest(t, http.MethodPut, "api/notes", map[string]any{"id": noteIDs[0], "text": "   "}, nil)
+	assert.Equal(t, http.StatusBadRequest, ret.code)
+	ret = apiRequest(t, http.MethodPut, "api/notes", map[string]any{"id": 999999999, "text": "Текст"}, nil)
+	assert.Equal(t, http.StatusNotFound, ret.code)
+
+	// Удаление
+	ret = apiRequest(t, http.MethodDelete, fmt.Sprintf("api/notes?id=%.0f", noteIDs[1]), nil, nil)
+	assert.Equal(t, http.StatusOK, ret.code, ret.body)
+	list = notes(t, id)
+	if assert.Len(t, list, 1) {
+		assert.Equal(t, noteIDs[0], list[0]["id"])
+	}
+	ret = apiRequest(t, http.MethodDelete, fmt.Sprintf("api/notes?id=%.0f", noteIDs[1]), nil, nil)
+	assert.Equal(t, http.StatusNotFound, ret.code)
+
+	ret = apiRequest(t, http.MethodPost, "api/notes", map[string]any{"task_id": 999999999, "text": "Текст"}, nil)
+	assert.Equal(t, http.StatusNotFound, ret.code)
+	ret = apiRequest(t, http.MethodGet, "api/notes?task_id=999999999", nil, nil)
+	assert.Equal(t, http.StatusNotFound, ret.code)
+}
@@ -0,0 +1,59 @@
+package tests
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTasksCursor(t *testing.T) {
+	const tag = "test-pagination"
+	add := func(date string) int64 {
+		return newTask(t, map[string]any{
+			"date":  date,
+			"title": "Страница " + date,
+			"tags":  []string{tag},
+		})
+	}
+
+	var original []int64
+	for _, date := range []string{"20300201", "20300202", "20300203", "20300204", "20300205"} {
+		original = append(original, add(date))
+	}
+
+	page := func(cursor string) apiResponse {
+		query := url.Values{"tag": {tag}, "sort": {"date"}, "limit": {"2"}}
+		if cursor != "" {
+			query.Set("cursor", cursor)
+		}
+		ret := apiRequest(t, http.MethodGet, "api/tasks?"+query.Encode(), nil, nil)
+		assert.Equal(t, http.StatusOK, ret.code, ret.body)
+		return ret
+	}
+
+	ret := page("")
+	assert.Equal(t, original[:2], ids(ret))
+	assert.Equal(t, float64(5), ret.body["total"])
+	cursor, _ := ret.body["next_cursor"].(string)
+	assert.NotEmpty(t, cursor)
+
+	// Задачи, добавленные между запросами страниц, не сдвигают выборку:
+	// до курсора они не попадают в следующие страницы, после - попадают
+	before := add("20300101")
+	after := add("20300210")
+
+	seen := ids(ret)
+	for cursor != "" {
+		ret = page(cursor)
+		assert.Equal(t, float64(7), ret.body["total"])
+		seen = append(seen, ids(ret)...)
+		cursor, _ = ret.body["next_cursor"].(string)
+	}
+	assert.Equal(t, append(original, after), seen)
+	assert.NotContains(t, seen, before)
+
+	ret = apiRequest(t, http.MethodGet, "api/tasks?cursor=broken", nil, nil)
+	assert.Equal(t, http.StatusBadRequest, ret.code)
+}
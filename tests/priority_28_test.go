@@ -0,0 +1,56 @@
+package tests
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrioritySort(t *testing.T) {
+	add := func(date, title string, priority int) int64 {
+		return newTask(t, map[string]any{
+			"date":     date,
+			"title":    title,
+			"tags":     []string{"testpriority"},
+			"priority": priority,
+		})
+	}
+	// Без приоритета задача получает P4
+	low := add("20330701", "Б задача", 0)
+	urgent := add("20330701", "В задача", 1)
+	normal := add("20330701", "А задача", 2)
+	later := add("20330702", "Г задача", 1)
+
+	list := func(query string) []int64 {
+		ret := apiRequest(t, http.MethodGet, "api/tasks?tag=testpriority&"+query, nil, nil)
+		assert.Equal(t, http.StatusOK, ret.code, query, ret.body)
+		return ids(ret)
+	}
+
+	tests := []struct {
+		query string
+		want  []int64
+	}{
+		// Внутри одного дня срочные задачи идут первыми
+		{"", []int64{urgent, normal, low, later}},
+		{"sort=date", []int64{urgent, normal, low, later}},
+		{"sort=date&order=desc", []int64{later, urgent, normal, low}},
+		{"sort=priority", []int64{urgent, later, normal, low}},
+		{"sort=priority&order=desc", []int64{low, normal, urgent, later}},
+		{"sort=title", []int64{normal, low, urgent, later}},
+		{"sort=created", []int64{low, urgent, normal, later}},
+		{"sort=created&order=desc", []int64{later, normal, urgent, low}},
+	}
+	for _, v := range tests {
+		assert.Equal(t, v.want, list(v.query), v.query)
+	}
+
+	for _, query := range []string{"sort=size", "order=up"} {
+		ret := apiRequest(t, http.MethodGet, "api/tasks?"+query, nil, nil)
+		assert.Equal(t, http.StatusBadRequest, ret.code, query)
+	}
+	ret := apiRequest(t, http.MethodPost, "api/addtask",
+		map[string]any{"date": "20330701", "title": "Неверный приоритет", "priority": 5}, nil)
+	assert.Equal(t, http.StatusBadRequest, ret.code)
+}
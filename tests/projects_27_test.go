@@ -0,0 +1,91 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newProject создаёт проект и удаляет его после теста
+func newProject(t *testing.T, name string) int64 {
+	ret := apiRequest(t, http.MethodPost, "api/projects", map[string]any{"name": name, "color": "#3366CC"}, nil)
+	if !assert.Equal(t, http.StatusOK, ret.code, ret.body) {
+		t.FailNow()
+	}
+	id := int64(ret.body["id"].(float64))
+	t.Cleanup(func() {
+		apiRequest(t, http.MethodDelete, fmt.Sprintf("api/projects?id=%d", id), nil, nil)
+	})
+	return id
+}
+
+func TestProjectFilter(t *testing.T) {
+	home := newProject(t, "Тест проектов дом")
+	work := newProject(t, "Тест проектов работа")
+
+	add := func(title string, project int64) int64 {
+		return newTask(t, map[string]any{
+			"date":       "20330601",
+			"title":      title,
+			"comment":    "testprojects",
+			"project_id": project,
+		})
+	}
+	cook := add("Приготовить ужин", home)
+	report := add("Написать отчёт", work)
+	inbox := add("Разобрать входящие", 0)
+
+	list := func(query string) []int64 {
+		ret := apiRequest(t, http.MethodGet, "api/tasks?search=testprojects&"+query, nil, nil)
+		assert.Equal(t, http.StatusOK, ret.code, query, ret.body)
+		return ids(ret)
+	}
+
+	ret := apiRequest(t, http.MethodGet, fmt.Sprintf("api/task?id=%d", inbox), nil, nil)
+	assert.Equal(t, float64(1), ret.body["project_id"])
+
+	assert.Equal(t, []int64{cook}, list(fmt.Sprintf("project=%d", home)))
+	assert.Equal(t, []int64{report}, list(fmt.Sprintf("project=%d", work)))
+	assert.Equal(t, []int64{cook, report, inbox}, list(""))
+	ret = apiRequest(t, http.MethodGet, "api/tasks?project=x", nil, nil)
+	assert.Equal(t, http.StatusBadRequest, ret.code)
+
+	// Фильтр project: в строке поиска принимает ID или имя без учёта регистра
+	search := url.Values{"search": {`project:"тест проектов ДОМ"`}}.Encode()
+	ret = apiRequest(t, http.MethodGet, "api/tasks?"+search, nil, nil)
+	assert.Equal(t, []int64{cook}, ids(ret))
+
+	// Перенос задачи между проектами
+	ret = apiRequest(t, http.MethodPost, fmt.Sprintf("api/task/move?id=%d", report),
+		map[string]any{"project_id": home}, nil)
+	assert.Equal(t, http.StatusOK, ret.code, ret.body)
+	assert.Equal(t, []int64{cook, report}, list(fmt.Sprintf("project=%d", home)))
+	assert.Empty(t, list(fmt.Sprintf("project=%d", work)))
+	ret = apiRequest(t, http.MethodPost, fmt.Sprintf("api/task/move?id=%d", report),
+		map[string]any{"project_id": 999999999}, nil)
+	assert.Equal(t, http.StatusBadRequest, ret.code)
+
+	// Задачи архивного проекта не видны в общем списке, но доступны
+	// по фильтру проекта; новые задачи в архивный проект не добавляются
+	ret = apiRequest(t, http.MethodPut, "api/projects",
+		map[string]any{"id": home, "name": "Тест проектов дом", "archived": true}, nil)
+	assert.Equal(t, http.StatusOK, ret.code, ret.body)
+	assert.Equal(t, []int64{inbox}, list(""))
+	assert.Equal(t, []int64{cook, report}, list(fmt.Sprintf("project=%d", home)))
+	ret = apiRequest(t, http.MethodPost, fmt.Sprintf("api/task/move?id=%d", inbox),
+		map[string]any{"project_id": home}, nil)
+	assert.Equal(t, http.StatusConflict, ret.code)
+
+	// После удаления проекта его задачи переходят во входящие
+	ret = apiRequest(t, http.MethodDelete, fmt.Sprintf("api/projects?id=%d", home), nil, nil)
+	assert.Equal(t, http.StatusOK, ret.code)
+	assert.Equal(t, []int64{cook, report, inbox}, list(""))
+	ret = apiRequest(t, http.MethodGet, fmt.Sprintf("api/task?id=%d", cook), nil, nil)
+	assert.Equal(t, float64(1), ret.body["project_id"])
+
+	ret = apiRequest(t, http.MethodDelete, "api/projects?id=1", nil, nil)
+	assert.Equal(t, http.StatusBadRequest, ret.code)
+}
@@ -0,0 +1,122 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReorderTasks(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	const date = "20330101"
+	var order []int64
+	for _, title := range []string{"Первая", "Вторая", "Третья", "Четвёртая"} {
+		order = append(order, newTask(t, map[string]any{
+			"date":  date,
+			"title": title,
+			"tags":  []string{"testreorder"},
+		}))
+	}
+
+	reorder := func(id int64, side string, neighbour int64) apiResponse {
+		return apiRequest(t, http.MethodPost, fmt.Sprintf("api/task/reorder?id=%d", id),
+			map[string]any{side: neighbour}, nil)
+	}
+	listed := func() []int64 {
+		query := url.Values{"search": {"tag:testreorder on:" + date}}
+		return ids(apiRequest(t, http.MethodGet, "api/tasks?"+query.Encode(), nil, nil))
+	}
+	move := func(id int64, pos int) {
+		i := slices.Index(order, id)
+		order = slices.Insert(slices.Delete(order, i, i+1), pos, id)
+	}
+
+	// Раз за разом вставляем задачу в одно и то же место: ранги между
+	// соседями становятся длиннее, но порядок должен сохраняться
+	for i := 0; i < 40; i++ {
+		if i%3 == 2 {
+			id := order[1]
+			ret := reorder(id, "before", order[0])
+			assert.Equal(t, http.StatusOK, ret.code, ret.body)
+			move(id, 0)
+		} else {
+			id := order[len(order)-1]
+			ret := reorder(id, "after", order[0])
+			assert.Equal(t, http.StatusOK, ret.code, ret.body)
+			move(id, 1)
+		}
+		if !assert.Equal(t, order, listed(), "перестановка %d", i) {
+			return
+		}
+	}
+
+	var ranks []string
+	err := db.Select(&ranks, `SELECT rank FROM scheduler WHERE date = ? AND id IN (?, ?, ?, ?) ORDER BY rank`,
+		date, order[0], order[1], order[2], order[3])
+	assert.NoError(t, err)
+	assert.Len(t, ranks, len(order))
+	for i, rank := range ranks {
+		assert.NotEmpty(t, rank)
+		assert.Less(t, len(rank), 40, "ранг растёт медленнее числа перестановок")
+		if i > 0 {
+			assert.Less(t, strings.Compare(ranks[i-1], rank), 0, "ранги должны быть различны")
+		}
+	}
+
+	// Перестановка меняет версию задачи и попадает в журнал
+	path := fmt.Sprintf("api/task?id=%d", order[0])
+	etag := apiRequest(t, http.MethodGet, path, nil, nil).header.Get("ETag")
+	ret := reorder(order[0], "after", order[1])
+	assert.Equal(t, http.StatusOK, ret.code, ret.body)
+	assert.NotEqual(t, etag, apiRequest(t, http.MethodGet, path, nil, nil).header.Get("ETag"))
+	assert.Equal(t, "reorder", auditEntries(t, order[0])[0]["action"])
+
+	// Вставка снова и снова между одними и теми же соседями удлиняет ранг,
+	// пока ранги дня не назначаются заново - с новой версией у каждой
+	// задачи, чей ранг изменился
+	first := order[0]
+	var firstRank string
+	assert.NoError(t, db.Get(&firstRank, `SELECT rank FROM scheduler WHERE id = ?`, first))
+	etag = apiRequest(t, http.MethodGet, fmt.Sprintf("api/task?id=%d", first), nil, nil).header.Get("ETag")
+	maxLen := 0
+	for i := 0; i < 300; i++ {
+		id := order[2]
+		ret := reorder(id, "after", first)
+		if !assert.Equal(t, http.StatusOK, ret.code, ret.body) {
+			return
+		}
+		move(id, 1)
+
+		var rank string
+		assert.NoError(t, db.Get(&rank, `SELECT rank FROM scheduler WHERE id = ?`, id))
+		maxLen = max(maxLen, len(rank))
+	}
+	assert.Equal(t, order, listed())
+	assert.LessOrEqual(t, maxLen, 32, "длина ранга ограничена")
+
+	var newRank string
+	assert.NoError(t, db.Get(&newRank, `SELECT rank FROM scheduler WHERE id = ?`, first))
+	assert.NotEqual(t, firstRank, newRank, "ранги дня назначены заново")
+	assert.NotEqual(t, etag, apiRequest(t, http.MethodGet, fmt.Sprintf("api/task?id=%d", first), nil, nil).header.Get("ETag"))
+	assert.Equal(t, "reorder", auditEntries(t, first)[0]["action"])
+
+	other := newTask(t, map[string]any{"date": "20330102", "title": "Другой день"})
+	for _, body := range []map[string]any{
+		{"after": other},
+		{"after": order[0]},
+		{"after": order[1], "before": order[2]},
+		{},
+	} {
+		ret = apiRequest(t, http.MethodPost, fmt.Sprintf("api/task/reorder?id=%d", order[0]), body, nil)
+		assert.Equal(t, http.StatusBadRequest, ret.code, body)
+	}
+	ret = reorder(999999999, "after", order[0])
+	assert.Equal(t, http.StatusNotFound, ret.code)
+}
@@ -0,0 +1,62 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSearchRelevance(t *testing.T) {
+	// Слабое совпадение раньше по дате, но ниже по релевантности
+	weak := newTask(t, map[string]any{
+		"date":    "20330401",
+		"title":   "Разобрать кладовку",
+		"comment": "Найти коробку, где лежит старый зебрафон, и отнести её на дачу вместе с лыжами",
+	})
+	strong := newTask(t, map[string]any{
+		"date":    "20330402",
+		"title":   "Починить зебрафон",
+		"comment": "Зебрафон не включается",
+	})
+	assert.Equal(t, []int64{strong, weak}, searchTasks(t, "зебрафон"))
+
+	// С явной сортировкой релевантность не учитывается
+	ret := apiRequest(t, http.MethodGet,
+		"api/tasks?"+url.Values{"search": {"зебрафон"}, "sort": {"date"}}.Encode(), nil, nil)
+	assert.Equal(t, http.StatusOK, ret.code, ret.body)
+	assert.Equal(t, []int64{weak, strong}, ids(ret))
+
+	// Слова ищутся как префиксы, все слова обязательны
+	assert.Equal(t, []int64{strong}, searchTasks(t, "зебраф почин"))
+	assert.Empty(t, searchTasks(t, "зебрафон пылесос"))
+}
+
+func TestSearchHighlight(t *testing.T) {
+	id := newTask(t, map[string]any{
+		"date":    "20330403",
+		"title":   `<b>Квазитрон</b> & "друзья"`,
+		"comment": `Запустить <script>alert(1)</script> квазитрон`,
+	})
+
+	ret := apiRequest(t, http.MethodGet, "api/tasks?"+url.Values{"search": {"квазитрон"}}.Encode(), nil, nil)
+	assert.Equal(t, http.StatusOK, ret.code, ret.body)
+	assert.Equal(t, []int64{id}, ids(ret))
+
+	task := ret.body["tasks"].([]any)[0].(map[string]any)
+	highlight, ok := task["highlight"].(map[string]any)
+	if !assert.True(t, ok, task) {
+		return
+	}
+	// Разметка из задачи экранируется, выделение остаётся тегом <mark>
+	assert.Equal(t, `&lt;b&gt;<mark>Квазитрон</mark>&lt;/b&gt; &amp; &#34;друзья&#34;`, highlight["title"])
+	assert.Equal(t, `Запустить &lt;script&gt;alert(1)&lt;/script&gt; <mark>квазитрон</mark>`, highlight["comment"])
+	// Исходные поля не меняются
+	assert.Equal(t, `<b>Квазитрон</b> & "друзья"`, task["title"])
+
+	// Без полнотекстового поиска подсветки нет
+	ret = apiRequest(t, http.MethodGet, fmt.Sprintf("api/task?id=%d", id), nil, nil)
+	assert.Nil(t, ret.body["highlight"])
+}
@@ -0,0 +1,85 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// setStatus переводит задачу в статус и возвращает код ответа
+func setStatus(t *testing.T, id int64, status string) int {
+	ret := apiRequest(t, http.MethodPost, fmt.Sprintf("api/task/status?id=%d", id),
+		map[string]any{"status": status}, nil)
+	return ret.code
+}
+
+func TestStatusTransitions(t *testing.T) {
+	statuses := []string{"todo", "in_progress", "done", "cancelled"}
+	allowed := map[string][]string{
+		"todo":        {"todo", "in_progress", "done", "cancelled"},
+		"in_progress": {"todo", "in_progress", "done", "cancelled"},
+		"done":        {"todo"},
+		"cancelled":   {"todo", "cancelled"},
+	}
+
+	for _, from := range statuses {
+		for _, to := range statuses {
+			name := from + " -> " + to
+			id := newTask(t, map[string]any{"date": "20331101", "title": "Переход " + name})
+			if from != "todo" {
+				assert.Equal(t, http.StatusOK, setStatus(t, id, from), name)
+			}
+
+			want := http.StatusConflict
+			if slices.Contains(allowed[from], to) {
+				want = http.StatusOK
+			}
+			assert.Equal(t, want, setStatus(t, id, to), name)
+
+			ret := apiRequest(t, http.MethodGet, fmt.Sprintf("api/task?id=%d", id), nil, nil)
+			if want == http.StatusOK {
+				assert.Equal(t, to, ret.body["status"], name)
+			} else {
+				assert.Equal(t, from, ret.body["status"], name)
+			}
+		}
+	}
+
+	id := newTask(t, map[string]any{"date": "20331101", "title": "Неизвестный статус"})
+	assert.Equal(t, http.StatusBadRequest, setStatus(t, id, "paused"))
+	assert.Equal(t, http.StatusNotFound, setStatus(t, 999999999, "in_progress"))
+}
+
+func TestDoneTaskKept(t *testing.T) {
+	id := newTask(t, map[string]any{"date": "20331102", "title": "Разовая задача", "tags": []string{"teststatus"}})
+	path := fmt.Sprintf("api/task?id=%d", id)
+
+	ret := apiRequest(t, http.MethodPost, fmt.Sprintf("api/task/done?id=%d", id), nil, nil)
+	assert.Equal(t, http.StatusOK, ret.code, ret.body)
+
+	// Выполненная разовая задача остаётся со статусом done и временем выполнения
+	ret = apiRequest(t, http.MethodGet, path, nil, nil)
+	assert.Equal(t, http.StatusOK, ret.code)
+	assert.Equal(t, "done", ret.body["status"])
+	assert.Equal(t, "20331102", ret.body["date"])
+	assert.NotEmpty(t, ret.body["completed_at"])
+
+	// По умолчанию список показывает только открытые задачи
+	ret = apiRequest(t, http.MethodGet, "api/tasks?tag=teststatus", nil, nil)
+	assert.NotContains(t, ids(ret), id)
+	ret = apiRequest(t, http.MethodGet, "api/tasks?tag=teststatus&status=done", nil, nil)
+	assert.Contains(t, ids(ret), id)
+	ret = apiRequest(t, http.MethodGet, "api/tasks?tag=teststatus&status=all", nil, nil)
+	assert.Contains(t, ids(ret), id)
+
+	// Повторно выполнить нельзя, вернуть в работу можно
+	ret = apiRequest(t, http.MethodPost, fmt.Sprintf("api/task/done?id=%d", id), nil, nil)
+	assert.Equal(t, http.StatusConflict, ret.code)
+	assert.Equal(t, http.StatusOK, setStatus(t, id, "todo"))
+	ret = apiRequest(t, http.MethodGet, path, nil, nil)
+	assert.Equal(t, "todo", ret.body["status"])
+	assert.Empty(t, ret.body["completed_at"])
+}
@@ -0,0 +1,89 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// tagByName возвращает метку из списка /api/tags или nil
+func tagByName(t *testing.T, name string) map[string]any {
+	ret := apiRequest(t, http.MethodGet, "api/tags", nil, nil)
+	assert.Equal(t, http.StatusOK, ret.code)
+	for _, item := range ret.body["tags"].([]any) {
+		if tag := item.(map[string]any); tag["name"] == name {
+			return tag
+		}
+	}
+	return nil
+}
+
+func TestTagFilter(t *testing.T) {
+	add := func(title string, tags ...string) int64 {
+		return newTask(t, map[string]any{"date": "20330501", "title": title, "tags": tags})
+	}
+	home := add("Вынести мусор", "testtagshome")
+	both := add("Купить лампочку", "TestTagsHome ", "testtagsshop")
+	shop := add("Купить хлеб", "testtagsshop")
+	t.Cleanup(func() {
+		for _, name := range []string{"testtagshome", "testtagsshop", "testtagsstore"} {
+			if tag := tagByName(t, name); tag != nil {
+				apiRequest(t, http.MethodDelete, fmt.Sprintf("api/tags?id=%.0f", tag["id"]), nil, nil)
+			}
+		}
+	})
+
+	// Метки хранятся в нижнем регистре без пробелов и без повторов
+	ret := apiRequest(t, http.MethodGet, fmt.Sprintf("api/task?id=%d", both), nil, nil)
+	assert.Equal(t, []any{"testtagshome", "testtagsshop"}, ret.body["tags"])
+
+	tests := []struct {
+		query string
+		want  []int64
+	}{
+		{"tag=testtagshome", []int64{home, both}},
+		{"tag=testtagsshop", []int64{both, shop}},
+		// Несколько меток - задача должна быть отмечена всеми
+		{"tag=testtagshome&tag=testtagsshop", []int64{both}},
+		{"tag=testtagshome,testtagsshop", []int64{both}},
+		{"tag=TestTagsShop", []int64{both, shop}},
+		{"tag=testtagsnone", nil},
+	}
+	for _, v := range tests {
+		ret := apiRequest(t, http.MethodGet, "api/tasks?"+v.query, nil, nil)
+		assert.Equal(t, http.StatusOK, ret.code, v.query)
+		assert.Equal(t, v.want, ids(ret), v.query)
+	}
+
+	// Счётчики для боковой панели не учитывают задачи в корзине
+	assert.Equal(t, float64(2), tagByName(t, "testtagsshop")["count"])
+	ret = apiRequest(t, http.MethodDelete, fmt.Sprintf("api/task?id=%d", shop), nil, nil)
+	assert.Equal(t, http.StatusOK, ret.code)
+	assert.Equal(t, float64(1), tagByName(t, "testtagsshop")["count"])
+
+	// Переименование и удаление метки видны в задачах
+	shopTag := tagByName(t, "testtagsshop")
+	ret = apiRequest(t, http.MethodPut, "api/tags",
+		map[string]any{"id": shopTag["id"], "name": "testtagsstore"}, nil)
+	assert.Equal(t, http.StatusOK, ret.code)
+	ret = apiRequest(t, http.MethodPut, "api/tags",
+		map[string]any{"id": shopTag["id"], "name": "testtagshome"}, nil)
+	assert.Equal(t, http.StatusConflict, ret.code)
+	ret = apiRequest(t, http.MethodGet, fmt.Sprintf("api/task?id=%d", both), nil, nil)
+	assert.Equal(t, []any{"testtagshome", "testtagsstore"}, ret.body["tags"])
+
+	homeTag := tagByName(t, "testtagshome")
+	ret = apiRequest(t, http.MethodDelete, fmt.Sprintf("api/tags?id=%.0f", homeTag["id"]), nil, nil)
+	assert.Equal(t, http.StatusOK, ret.code)
+	ret = apiRequest(t, http.MethodGet, "api/tasks?tag=testtagshome", nil, nil)
+	assert.Empty(t, ids(ret))
+	ret = apiRequest(t, http.MethodGet, fmt.Sprintf("api/task?id=%d", both), nil, nil)
+	assert.Equal(t, []any{"testtagsstore"}, ret.body["tags"])
+
+	for _, name := range []string{"", "a,b"} {
+		ret = apiRequest(t, http.MethodPost, "api/tags", map[string]any{"name": name}, nil)
+		assert.Equal(t, http.StatusBadRequest, ret.code, name)
+	}
+}
@@ -32,7 +32,14 @@ func TestDone(t *testing.T) {
 	ret, err := postJSON("api/task/done?id="+id, nil, http.MethodPost)
 	assert.NoError(t, err)
 	assert.Empty(t, ret)
-	notFoundTask(t, id)
+
+	// Выполненная одноразовая задача остаётся в базе со статусом done
+	body, err := requestJSON("api/task?id="+id, nil, http.MethodGet)
+	assert.NoError(t, err)
+	var done map[string]any
+	assert.NoError(t, json.Unmarshal(body, &done))
+	assert.Equal(t, "done", done["status"])
+	assert.NotEmpty(t, done["completed_at"])
 
 	id = addTask(t, task{
 		title:  "Проверить работу /api/task/done",
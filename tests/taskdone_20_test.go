@@ -0,0 +1,41 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDoneExpectedDate(t *testing.T) {
+	id := newTask(t, map[string]any{
+		"date":   "20330601",
+		"title":  "Полить цветы",
+		"repeat": "d 5",
+	})
+	path := fmt.Sprintf("api/task?id=%d", id)
+	done := func(query string, header map[string]string) int {
+		return apiRequest(t, http.MethodPost, fmt.Sprintf("api/task/done?id=%d%s", id, query), nil, header).code
+	}
+
+	// Два нажатия с одной и той же датой сдвигают задачу только один раз
+	assert.Equal(t, http.StatusOK, done("&date=20330601", nil))
+	assert.Equal(t, http.StatusConflict, done("&date=20330601", nil))
+	assert.Equal(t, "20330606", apiRequest(t, http.MethodGet, path, nil, nil).body["date"])
+
+	// То же для версии из ETag
+	etag := apiRequest(t, http.MethodGet, path, nil, nil).header.Get("ETag")
+	assert.Equal(t, http.StatusOK, done("", map[string]string{"If-Match": etag}))
+	assert.Equal(t, http.StatusPreconditionFailed, done("", map[string]string{"If-Match": etag}))
+	assert.Equal(t, "20330611", apiRequest(t, http.MethodGet, path, nil, nil).body["date"])
+
+	assert.Equal(t, http.StatusBadRequest, done("&date=11.06.2033", nil))
+	assert.Equal(t, http.StatusOK, done("", nil))
+	assert.Equal(t, "20330616", apiRequest(t, http.MethodGet, path, nil, nil).body["date"])
+
+	ret := apiRequest(t, http.MethodGet, fmt.Sprintf("api/task/history?id=%d", id), nil, nil)
+	assert.Equal(t, http.StatusOK, ret.code, ret.body)
+	history, _ := ret.body["completions"].([]any)
+	assert.Len(t, history, 3)
+}
@@ -0,0 +1,86 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTemplateDates(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	today := time.Now().Format(`20060102`)
+	getTask := func(ret apiResponse) map[string]any {
+		if !assert.Equal(t, http.StatusOK, ret.code, ret.body) {
+			t.FailNow()
+		}
+		id := int64(ret.body["id"].(float64))
+		deleteAfterTest(t, id)
+		return apiRequest(t, http.MethodGet, fmt.Sprintf("api/task?id=%d", id), nil, nil).body
+	}
+
+	name := fmt.Sprintf("Тест шаблона %d", time.Now().UnixNano())
+	ret := apiRequest(t, http.MethodPost, "api/templates", map[string]any{
+		"name":       name,
+		"title":      "Отчёт за {{date}}",
+		"comment":    "Сдать до {{due}}",
+		"due_offset": 2,
+	}, nil)
+	if !assert.Equal(t, http.StatusOK, ret.code, ret.body) {
+		return
+	}
+	templateID := int64(ret.body["id"].(float64))
+	t.Cleanup(func() {
+		apiRequest(t, http.MethodDelete, fmt.Sprintf("api/templates?id=%d", templateID), nil, nil)
+	})
+	instantiate := func(anchor string) apiResponse {
+		return apiRequest(t, http.MethodPost, fmt.Sprintf("api/templates/%d/instantiate", templateID),
+			map[string]any{"anchor": anchor}, nil)
+	}
+
+	task := getTask(instantiate("20330801"))
+	assert.Equal(t, "20330801", task["date"])
+	assert.Equal(t, "20330803", task["due"])
+	assert.Equal(t, "Отчёт за 01.08.2033", task["title"])
+	assert.Equal(t, "Сдать до 03.08.2033", task["comment"])
+
+	// Дата в прошлом переносится на сегодня до подстановки в текст
+	task = getTask(instantiate("20200101"))
+	assert.Equal(t, today, task["date"])
+	assert.Equal(t, "Отчёт за "+time.Now().Format(`02.01.2006`), task["title"])
+
+	// Копия задачи на дату в прошлом ведёт себя так же
+	source := newTask(t, map[string]any{"date": "20330810", "due": "20330812", "title": "Исходная задача"})
+	clone := func(anchor string) apiResponse {
+		return apiRequest(t, http.MethodPost, fmt.Sprintf("api/task/clone?id=%d", source),
+			map[string]any{"anchor": anchor}, nil)
+	}
+	task = getTask(clone("20330820"))
+	assert.Equal(t, "20330820", task["date"])
+	assert.Equal(t, "20330822", task["due"])
+	task = getTask(clone("20200101"))
+	assert.Equal(t, today, task["date"])
+
+	// Ошибка проверки дат - ошибка запроса, а не сервера
+	res, err := db.Exec(`INSERT INTO templates (name, title, repeat) VALUES (?, 'Сломанный', 'x 1')`, name+" repeat")
+	if !assert.NoError(t, err) {
+		return
+	}
+	broken, err := res.LastInsertId()
+	assert.NoError(t, err)
+	t.Cleanup(func() {
+		apiRequest(t, http.MethodDelete, fmt.Sprintf("api/templates?id=%d", broken), nil, nil)
+	})
+	ret = apiRequest(t, http.MethodPost, fmt.Sprintf("api/templates/%d/instantiate", broken),
+		map[string]any{"anchor": "20200101"}, nil)
+	assert.Equal(t, http.StatusBadRequest, ret.code, ret.body)
+
+	ret = apiRequest(t, http.MethodPost, "api/templates/999999999/instantiate", nil, nil)
+	assert.Equal(t, http.StatusNotFound, ret.code)
+	ret = clone("2033-08-20")
+	assert.Equal(t, http.StatusBadRequest, ret.code)
+}
@@ -0,0 +1,108 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrash(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	id := newTask(t, map[string]any{
+		"date":  "20300120",
+		"title": "Задача для корзины",
+	})
+	path := fmt.Sprintf("api/task?id=%d", id)
+
+	// trashed обходит корзину по страницам размера limit
+	trashed := func(limit int) []int64 {
+		var total int
+		err := db.Get(&total, `SELECT COUNT(*) FROM scheduler WHERE deleted_at IS NOT NULL`)
+		assert.NoError(t, err)
+
+		var list []int64
+		query := url.Values{"limit": {strconv.Itoa(limit)}}
+		for {
+			ret := apiRequest(t, http.MethodGet, "api/trash?"+query.Encode(), nil, nil)
+			if !assert.Equal(t, http.StatusOK, ret.code, ret.body) {
+				return list
+			}
+			assert.Equal(t, float64(total), ret.body["total"], "total - все задачи в корзине")
+			page := ids(ret)
+			assert.LessOrEqual(t, len(page), limit)
+			list = append(list, page...)
+
+			cursor, _ := ret.body["next_cursor"].(string)
+			if cursor == "" {
+				break
+			}
+			query.Set("cursor", cursor)
+		}
+		assert.Len(t, list, total)
+		return list
+	}
+	inTrash := func() bool {
+		return slices.Contains(trashed(50), id)
+	}
+
+	ret := apiRequest(t, http.MethodDelete, path, nil, nil)
+	assert.Equal(t, http.StatusOK, ret.code, ret.body)
+	ret = apiRequest(t, http.MethodGet, path, nil, nil)
+	assert.Equal(t, http.StatusNotFound, ret.code)
+	assert.True(t, inTrash())
+
+	// Восстановление
+	ret = apiRequest(t, http.MethodPost, fmt.Sprintf("api/trash/restore?id=%d", id), nil, nil)
+	assert.Equal(t, http.StatusOK, ret.code, ret.body)
+	ret = apiRequest(t, http.MethodGet, path, nil, nil)
+	assert.Equal(t, http.StatusOK, ret.code)
+	assert.Equal(t, "Задача для корзины", ret.body["title"])
+	assert.False(t, inTrash())
+
+	// Задачу не из корзины нельзя ни восстановить, ни удалить окончательно
+	ret = apiRequest(t, http.MethodPost, fmt.Sprintf("api/trash/restore?id=%d", id), nil, nil)
+	assert.Equal(t, http.StatusNotFound, ret.code)
+	ret = apiRequest(t, http.MethodDelete, fmt.Sprintf("api/trash?id=%d", id), nil, nil)
+	assert.Equal(t, http.StatusNotFound, ret.code)
+	ret = apiRequest(t, http.MethodGet, path, nil, nil)
+	assert.Equal(t, http.StatusOK, ret.code)
+
+	// Окончательное удаление
+	ret = apiRequest(t, http.MethodDelete, path, nil, nil)
+	assert.Equal(t, http.StatusOK, ret.code, ret.body)
+	ret = apiRequest(t, http.MethodDelete, fmt.Sprintf("api/trash?id=%d", id), nil, nil)
+	assert.Equal(t, http.StatusOK, ret.code, ret.body)
+	assert.False(t, inTrash())
+
+	var count int
+	err := db.Get(&count, `SELECT COUNT(*) FROM scheduler WHERE id = ?`, id)
+	assert.NoError(t, err)
+	assert.Zero(t, count)
+
+	ret = apiRequest(t, http.MethodPost, fmt.Sprintf("api/trash/restore?id=%d", id), nil, nil)
+	assert.Equal(t, http.StatusNotFound, ret.code)
+
+	// Корзина листается курсором, начиная с удалённых последними
+	var deleted []int64
+	for i := 0; i < 5; i++ {
+		id := newTask(t, map[string]any{"date": "20300121", "title": fmt.Sprintf("Корзина %d", i)})
+		ret = apiRequest(t, http.MethodDelete, fmt.Sprintf("api/task?id=%d", id), nil, nil)
+		assert.Equal(t, http.StatusOK, ret.code, ret.body)
+		deleted = append([]int64{id}, deleted...)
+	}
+	list := trashed(2)
+	assert.Equal(t, deleted, list[:len(deleted)])
+	assert.Len(t, slices.Compact(slices.Sorted(slices.Values(list))), len(list), "страницы не пересекаются")
+
+	for _, query := range []string{"cursor=abc", "limit=0", "limit=x"} {
+		ret = apiRequest(t, http.MethodGet, "api/trash?"+query, nil, nil)
+		assert.Equal(t, http.StatusBadRequest, ret.code, query)
+	}
+}